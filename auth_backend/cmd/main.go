@@ -1,8 +1,11 @@
 package main
 
 import (
+	"log"
 	"net/http"
+	"os"
 
+	"github.com/devesh121/userAuth/internals/middlewares"
 	"github.com/devesh121/userAuth/internals/routes"
 	"github.com/devesh121/userAuth/monitoring/metrics"
 	"github.com/devesh121/userAuth/pkg/config"
@@ -12,6 +15,18 @@ import (
 )
 
 func main() {
+	// Maintenance commands, e.g. "audit verify" or "migrate up"
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "audit":
+			runAuditCommand(os.Args[2:])
+			return
+		case "migrate":
+			runMigrateCommand(os.Args[2:])
+			return
+		}
+	}
+
 	// Load configuration
 	config.LoadEnv()
 	config.ConnectDB()
@@ -19,6 +34,20 @@ func main() {
 	// Initialize metrics
 	metrics.Initialize()
 
+	// Count and time every database query, logging slow ones with their request ID
+	if err := config.DB.Use(metrics.NewGormPlugin(config.GetDBSlowQueryThreshold())); err != nil {
+		log.Fatalf("Failed to instrument the database: %v", err)
+	}
+
+	// Sign the audit chain every AUDIT_CHECKPOINT_INTERVAL
+	startAuditCheckpoints()
+
+	// Purge users deleted longer ago than USER_RETENTION_DAYS every USER_PURGE_INTERVAL
+	routes.StartUserPurge()
+
+	// Keep the active users gauge at the number of users with a live session
+	routes.StartActiveUsersGauge()
+
 	// Create router without default middleware
 	r := gin.New()
 
@@ -28,6 +57,9 @@ func main() {
 	// Add metrics middleware globally EXCEPT for /metrics endpoint
 	r.Use(metrics.MetricsMiddleware())
 
+	// Tag every request with an ID, carried in its context down to the audit log
+	r.Use(middlewares.RequestID())
+
 	// Setup metrics endpoint
 	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(
 		prometheus.DefaultGatherer,
@@ -37,9 +69,18 @@ func main() {
 	// Health check endpoint
 	r.GET("/health", healthCheck)
 
-	// Setup API routes
-	api := r.Group("/api/v1")
+	// Errors are answered as application/problem+json, with this prefix in their type URI
+	middlewares.ProblemTypeBase = config.GetProblemTypeBase()
+
+	// Setup API routes, with one mapping from errors to responses and a deadline for their database queries
+	api := r.Group("/api/v1", middlewares.ErrorHandler(), middlewares.DBTimeout(config.GetDBTimeout()))
 	routes.UserRoutes(api)
+	routes.FederationRoutes(api)
+	routes.SAMLRoutes(api)
+	routes.AdminRoutes(api)
+
+	// OAuth token introspection and revocation for resource servers
+	routes.OAuthRoutes(r.Group("", middlewares.DBTimeout(config.GetDBTimeout())))
 
 	println("✅ Server started at http://localhost:8080")
 	r.Run("0.0.0.0:8080")
@@ -65,7 +106,7 @@ func healthCheck(c *gin.Context) {
 			return
 		}
 
-		err = sqlDB.Ping()
+		err = sqlDB.PingContext(c.Request.Context())
 		if err != nil {
 			status = "down"
 			message = "Database connection failed"
@@ -88,9 +129,7 @@ func healthCheck(c *gin.Context) {
 		return
 	}
 
-	// Only record metrics if everything is healthy
 	if isHealthy {
-		metrics.ActiveUsers.Set(1) // Example metric
 		c.JSON(http.StatusOK, gin.H{
 			"status":  status,
 			"message": message,
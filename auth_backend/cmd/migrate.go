@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/devesh121/userAuth/pkg/migrate"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up                  apply all pending migrations
+  down [n]            revert the last n migrations (default 1)
+  status              list migrations and whether they are applied
+  create <name> [dir] add an empty up/down pair (default: one per dialect in pkg/migrate/sql)`
+
+// runMigrateCommand runs "migrate <command>" and exits
+func runMigrateCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	// create only writes files in the source tree
+	if args[0] == "create" {
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		// Every dialect needs the same migration, so the versions stay in step
+		dirs := []string{"pkg/migrate/sql/postgres", "pkg/migrate/sql/sqlite"}
+		if len(args) > 2 {
+			dirs = args[2:3]
+		}
+		for _, dir := range dirs {
+			paths, err := migrate.Create(dir, args[1])
+			if err != nil {
+				log.Fatalf("failed to create migration: %v", err)
+			}
+			for _, path := range paths {
+				fmt.Println(path)
+			}
+		}
+		return
+	}
+
+	config.LoadEnv()
+	config.OpenDB()
+	migrator, err := config.NewMigrator()
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("failed to migrate: %v", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		fmt.Printf("database is at version %d\n", migrator.Latest())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("invalid number of steps: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("failed to revert: %v", err)
+		}
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("failed to read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,33 @@
+// Package cache keeps short-lived copies of values in process memory or in Redis.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devesh121/userAuth/pkg/config"
+)
+
+// Store is implemented by every cache backend. Values are opaque bytes, so callers never share
+// a cached value with each other.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error) // false when the key is missing or expired
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NewStore builds the backend selected by USER_CACHE: "memory" or "redis". It returns nil when
+// caching is off.
+func NewStore(cfg config.UserCacheConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", config.UserCacheOff:
+		return nil, nil
+	case config.UserCacheMemory:
+		return NewLRU(cfg.Size), nil
+	case config.UserCacheRedis:
+		return NewRedis(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown user cache backend %q", cfg.Backend)
+	}
+}
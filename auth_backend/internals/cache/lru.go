@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruStore is a Store in process memory holding at most size entries, the least recently used
+// one being dropped first
+type lruStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+// lruEntry is the value of an element of lruStore.order
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU returns a Store in process memory holding at most size entries (default: 10000)
+func NewLRU(size int) Store {
+	if size <= 0 {
+		size = 10000
+	}
+	return &lruStore{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns a copy of the value of key
+func (s *lruStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, found := s.entries[key]
+	if !found {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !time.Now().Before(entry.expires) {
+		s.remove(element)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(element)
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+// Set stores a copy of value under key until ttl passes, evicting the least recently used entry
+// when full
+func (s *lruStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &lruEntry{key: key, value: append([]byte(nil), value...), expires: time.Now().Add(ttl)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, found := s.entries[key]; found {
+		element.Value = entry
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(entry)
+	if s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Delete removes the keys, missing ones are ignored
+func (s *lruStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if element, found := s.entries[key]; found {
+			s.remove(element)
+		}
+	}
+	return nil
+}
+
+// remove drops an element, the caller holds s.mu
+func (s *lruStore) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds a command when the context has no deadline
+const redisTimeout = time.Second
+
+// redisStore is a Store on a Redis server
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedis returns a Store on the server of a redis://[user:password@]host:port[/db] URL, or
+// rediss:// for TLS. Connections are opened on first use.
+func NewRedis(rawURL string) (Store, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL %q, want redis[s]://[user:password@]host:port[/db]: %w", rawURL, err)
+	}
+	opts.DialTimeout = redisTimeout
+	opts.ReadTimeout = redisTimeout
+	opts.WriteTimeout = redisTimeout
+	opts.ContextTimeoutEnabled = true // a request deadline shorter than redisTimeout wins
+	return &redisStore{client: redis.NewClient(opts)}, nil
+}
+
+// Get returns the value of key
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key until ttl passes
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete removes the keys
+func (s *redisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
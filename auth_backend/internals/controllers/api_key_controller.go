@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController handles the personal API key endpoints of the logged in user
+type APIKeyController struct {
+	apiKeyService services.APIKeyService
+}
+
+// NewAPIKeyController returns a new controller with injected service
+func NewAPIKeyController(service services.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: service,
+	}
+}
+
+// CreateAPIKey handles POST /users/me/api-keys
+func (ac *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	key, err := ac.apiKeyService.CreateAPIKeyService(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "api key created, store it now: it won't be shown again",
+		"data":    key,
+	})
+}
+
+// ListAPIKeys handles GET /users/me/api-keys
+func (ac *APIKeyController) ListAPIKeys(c *gin.Context) {
+	keys, err := ac.apiKeyService.ListAPIKeysService(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /users/me/api-keys/:key_id
+func (ac *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("key_id"))
+	if err != nil || keyID < 1 {
+		invalidField(c, "key_id", "min", "must be a positive integer")
+		return
+	}
+
+	if err := ac.apiKeyService.RevokeAPIKeyService(c.Request.Context(), c.GetUint("user_id"), uint(keyID)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController serves the audit log to admins
+type AuditController struct {
+	auditService services.AuditService
+}
+
+// NewAuditController returns a new controller with injected service
+func NewAuditController(service services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: service,
+	}
+}
+
+// ListAuditEvents handles GET /admin/audit-events
+func (ac *AuditController) ListAuditEvents(c *gin.Context) {
+	var query dto.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	page, err := ac.auditService.QueryEventsService(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// VerifyAuditChain handles GET /admin/audit-events/verify
+func (ac *AuditController) VerifyAuditChain(c *gin.Context) {
+	result, err := ac.auditService.VerifyChainService(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportAuditEvents handles GET /admin/audit-events/export, streaming the chain as JSON Lines
+func (ac *AuditController) ExportAuditEvents(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	c.Status(http.StatusOK)
+
+	// Headers are gone once streaming started, a failure can only cut the file short
+	if err := ac.auditService.ExportService(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("audit export failed: %v", err)
+	}
+}
+
+// CreateCheckpoint handles POST /admin/audit-checkpoints, signing the current head of the chain
+func (ac *AuditController) CreateCheckpoint(c *gin.Context) {
+	checkpoint, err := ac.auditService.CheckpointService(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if checkpoint == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "no new events since the last checkpoint"})
+		return
+	}
+	c.JSON(http.StatusCreated, checkpoint)
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// EmailVerificationController serves the links of verification and email change emails. Mail
+// scanners open links ahead of the user, so following a link (GET) only shows a page whose
+// button POSTs the token: nothing changes until the user presses it.
+type EmailVerificationController struct {
+	emailService services.EmailVerificationService
+}
+
+// NewEmailVerificationController returns a new controller with injected service
+func NewEmailVerificationController(service services.EmailVerificationService) *EmailVerificationController {
+	return &EmailVerificationController{
+		emailService: service,
+	}
+}
+
+// linkPage is the text of the page of a mailed link, before and after its button is pressed
+type linkPage struct {
+	Title  string
+	Text   string
+	Button string
+	Done   string
+}
+
+var (
+	verifyEmailPage = linkPage{
+		Title:  "Verify your email",
+		Text:   "Confirm that this email address is yours.",
+		Button: "Verify my email",
+		Done:   "Your email is verified.",
+	}
+	confirmEmailPage = linkPage{
+		Title:  "Confirm your new email",
+		Text:   "Confirm that your account should use this email address from now on.",
+		Button: "Use this email",
+		Done:   "Your account now uses this email.",
+	}
+	revertEmailPage = linkPage{
+		Title:  "Keep your email",
+		Text:   "Undo the change of your account's email and sign out of every session. Change your password afterwards if you didn't ask for the change.",
+		Button: "Keep my email and sign out everywhere",
+		Done:   "Your account keeps this email and all its sessions are signed out.",
+	}
+)
+
+// linkPageTemplate renders a linkPage: with Token, the form posting it to Action, otherwise Done
+var linkPageTemplate = template.Must(template.New("link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Page.Title}}</title>
+</head>
+<body>
+<h1>{{.Page.Title}}</h1>
+{{if .Token}}<p>{{.Page.Text}}</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Page.Button}}</button>
+</form>{{else}}<p>{{.Page.Done}}</p>{{end}}
+</body>
+</html>
+`))
+
+// VerifyEmailPage handles GET /users/verify-email?token=, the link mailed on registration
+func (ec *EmailVerificationController) VerifyEmailPage(c *gin.Context) {
+	ec.showLink(c, verifyEmailPage)
+}
+
+// VerifyEmail handles POST /users/verify-email, sent by the page of the link
+func (ec *EmailVerificationController) VerifyEmail(c *gin.Context) {
+	ec.applyLink(c, verifyEmailPage, ec.emailService.VerifyEmailService)
+}
+
+// ConfirmEmailPage handles GET /users/confirm-email?token=, the link mailed to a requested new email
+func (ec *EmailVerificationController) ConfirmEmailPage(c *gin.Context) {
+	ec.showLink(c, confirmEmailPage)
+}
+
+// ConfirmEmail handles POST /users/confirm-email, sent by the page of the link
+func (ec *EmailVerificationController) ConfirmEmail(c *gin.Context) {
+	ec.applyLink(c, confirmEmailPage, ec.emailService.ConfirmEmailChangeService)
+}
+
+// RevertEmailPage handles GET /users/revert-email?token=, the link mailed to the email being changed
+func (ec *EmailVerificationController) RevertEmailPage(c *gin.Context) {
+	ec.showLink(c, revertEmailPage)
+}
+
+// RevertEmail handles POST /users/revert-email, sent by the page of the link
+func (ec *EmailVerificationController) RevertEmail(c *gin.Context) {
+	ec.applyLink(c, revertEmailPage, ec.emailService.RevertEmailChangeService)
+}
+
+// showLink answers a followed link with its page, without looking at the token
+func (ec *EmailVerificationController) showLink(c *gin.Context, page linkPage) {
+	token := c.Query("token")
+	if token == "" {
+		invalidField(c, "token", "required", "is required")
+		return
+	}
+	renderLinkPage(c, page, token)
+}
+
+// applyLink passes the token posted by the page of a link, or sent as JSON, to the service and
+// answers the user: the JSON user, or the page saying it's done when the page posted it
+func (ec *EmailVerificationController) applyLink(c *gin.Context, page linkPage, service func(context.Context, string) (*dto.UserResponse, error)) {
+	// The token is only read from the body, a link opened with POST changes nothing either
+	fromPage := c.ContentType() == binding.MIMEPOSTForm
+	var req dto.EmailTokenRequest
+	var err error
+	if fromPage {
+		err = c.ShouldBindWith(&req, binding.FormPost)
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	user, err := service(c.Request.Context(), req.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if fromPage {
+		renderLinkPage(c, page, "")
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// renderLinkPage writes the page of a link, with the form posting token if it's set
+func renderLinkPage(c *gin.Context, page linkPage, token string) {
+	var body bytes.Buffer
+	data := struct {
+		Page   linkPage
+		Action string
+		Token  string
+	}{page, c.Request.URL.Path, token}
+	if err := linkPageTemplate.Execute(&body, data); err != nil {
+		c.Error(err)
+		return
+	}
+	// The token is in the URL: keep it out of caches and out of the Referer of other sites
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
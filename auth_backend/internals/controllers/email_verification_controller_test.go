@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/middlewares"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingEmailService records the tokens it is asked to revert an email change with
+type recordingEmailService struct {
+	services.EmailVerificationService
+	reverted []string
+}
+
+func (s *recordingEmailService) RevertEmailChangeService(ctx context.Context, token string) (*dto.UserResponse, error) {
+	s.reverted = append(s.reverted, token)
+	return &dto.UserResponse{ID: 1, Email: "ann@example.com"}, nil
+}
+
+// TestEmailLinkNeedsPost checks that opening a mailed link only shows its page, and that the
+// token posted by the page, or sent as JSON, applies it
+func TestEmailLinkNeedsPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	emails := &recordingEmailService{}
+	ec := NewEmailVerificationController(emails)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.GET("/users/revert-email", ec.RevertEmailPage)
+	r.POST("/users/revert-email", ec.RevertEmail)
+
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// A scanner opening the link changes nothing, the page posts the token back
+	w := send(httptest.NewRequest(http.MethodGet, "/users/revert-email?token=tok%3C1%3E", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Contains(t, w.Body.String(), `<form method="post" action="/users/revert-email">`)
+	assert.Contains(t, w.Body.String(), `value="tok&lt;1&gt;"`)
+	assert.Empty(t, emails.reverted)
+	assert.Equal(t, http.StatusBadRequest, send(httptest.NewRequest(http.MethodGet, "/users/revert-email", nil)).Code)
+
+	// A token in the URL of a POST isn't read
+	req := httptest.NewRequest(http.MethodPost, "/users/revert-email?token=tok", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = send(req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var problem dto.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, []dto.FieldError{{Field: "token", Rule: "required", Message: "is required"}}, problem.Errors)
+	assert.Empty(t, emails.reverted)
+
+	// The page's form is answered with the page, JSON with the user
+	req = httptest.NewRequest(http.MethodPost, "/users/revert-email", strings.NewReader(url.Values{"token": {"tok-form"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = send(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), revertEmailPage.Done)
+	assert.NotContains(t, w.Body.String(), "<form")
+
+	req = httptest.NewRequest(http.MethodPost, "/users/revert-email", strings.NewReader(`{"token": "tok-json"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = send(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"email":"ann@example.com"`)
+	assert.Equal(t, []string{"tok-form", "tok-json"}, emails.reverted)
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+const federationStateCookie = "federation_state"
+
+// FederationController handles login through upstream identity providers
+type FederationController struct {
+	federationService services.FederationService
+}
+
+// NewFederationController returns a new controller with injected service
+func NewFederationController(service services.FederationService) *FederationController {
+	return &FederationController{
+		federationService: service,
+	}
+}
+
+// Login redirects the browser to the provider's login page
+func (fc *FederationController) Login(c *gin.Context) {
+	authURL, state, err := fc.federationService.BeginLoginService(c.Param("provider"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Remember state, nonce and PKCE verifier until the provider redirects back (10 minutes)
+	value := strings.Join([]string{state.State, state.Nonce, state.CodeVerifier}, ".")
+	c.SetCookie(federationStateCookie, value, 600, "/", "", false, true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the redirect back from the provider and logs the user in
+func (fc *FederationController) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.Error(services.NewError(services.ErrUnauthorized, "provider_error", "identity provider returned: "+errCode))
+		return
+	}
+
+	// Step 1: Check the state against the cookie set in Login
+	cookie, err := c.Cookie(federationStateCookie)
+	parts := strings.Split(cookie, ".")
+	if err != nil || len(parts) != 3 || c.Query("state") == "" || c.Query("state") != parts[0] {
+		invalidRequest(c, "Invalid or expired login state")
+		return
+	}
+	c.SetCookie(federationStateCookie, "", -1, "/", "", false, true)
+
+	state := dto.FederationState{State: parts[0], Nonce: parts[1], CodeVerifier: parts[2]}
+
+	// Step 2: Complete the login
+	resp, token, err := fc.federationService.CompleteLoginService(c.Request.Context(), c.Param("provider"), c.Query("code"), state, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Step 3: Same session cookie as a password login
+	c.SetCookie("auth_token", token, 3600*24, "/", "localhost", false, true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "login successful",
+		"data":    resp,
+	})
+}
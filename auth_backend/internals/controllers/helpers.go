@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Report validation errors under the names clients send (json, else form tag) instead of the Go field names
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// clientInfo collects the caller's IP and user agent for session records
+func clientInfo(c *gin.Context) dto.ClientInfo {
+	return dto.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// actor identifies the logged in caller for the audit log
+func actor(c *gin.Context) dto.Actor {
+	return dto.Actor{
+		ID:         c.GetUint("user_id"),
+		Email:      c.GetString("user_email"),
+		ClientInfo: clientInfo(c),
+	}
+}
+
+// pageLink returns a Link header entry for another page of the current request: the same
+// query without cursor and offset, plus the given parameters
+func pageLink(c *gin.Context, rel string, params map[string]string) string {
+	query := c.Request.URL.Query()
+	query.Del("cursor")
+	query.Del("offset")
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	target := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+}
+
+// versionETag is the strong ETag of a user at a version, e.g. "3"
+func versionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchVersion returns the version named by If-Match, 0 for "*". Without the header the
+// update is refused with 428, and a weak or foreign ETag can never match (RFC 9110 13.1.1),
+// so it is refused with 412; ok is false then and the error is reported.
+func ifMatchVersion(c *gin.Context) (version uint, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch {
+	case header == "":
+		c.Error(services.ErrVersionRequired)
+		return 0, false
+	case header == "*":
+		return 0, true
+	case strings.Contains(header, ","):
+		invalidField(c, "If-Match", "etag", "must be the one ETag of the user or *")
+		return 0, false
+	}
+
+	tag, found := strings.CutPrefix(header, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	parsed, err := strconv.ParseUint(tag, 10, 0)
+	if !found || !closed || err != nil || parsed == 0 {
+		c.Error(services.ErrVersionMismatch)
+		return 0, false
+	}
+	return uint(parsed), true
+}
+
+// noneMatch reports whether If-None-Match lists etag, compared weakly, so 304 can be answered
+func noneMatch(c *gin.Context, etag string) bool {
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePatchType is the media type of JSON merge patches (RFC 7396)
+const mergePatchType = "application/merge-patch+json"
+
+// bindMergePatch decodes a JSON merge patch into target, a struct of pointer fields, and
+// validates it. Fields the target doesn't have and null (removing a field) are rejected, so no
+// part of a patch is silently ignored. Errors are reported and ok is false.
+func bindMergePatch(c *gin.Context, target any) (ok bool) {
+	if ct := c.ContentType(); ct != mergePatchType && ct != binding.MIMEJSON {
+		c.Error(services.NewError(services.ErrUnsupportedMediaType, "unsupported_media_type", "send the patch as "+mergePatchType))
+		return false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		invalidRequest(c, "The request body could not be read")
+		return false
+	}
+
+	// Step 1: Check the members of the patch object against the fields of target
+	var members map[string]json.RawMessage
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(body, &members); errors.As(err, &typeErr) || (err == nil && members == nil) {
+		invalidRequest(c, "The patch must be a JSON object")
+		return false
+	} else if err != nil {
+		bindingError(c, err)
+		return false
+	}
+	known := make(map[string]bool)
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(target).Elem()) {
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	var fields []dto.FieldError
+	for name, value := range members {
+		switch {
+		case !known[name]:
+			fields = append(fields, dto.FieldError{Field: name, Rule: "unknown", Message: "can't be changed"})
+		case string(value) == "null":
+			fields = append(fields, dto.FieldError{Field: name, Rule: "nonnull", Message: "can't be removed"})
+		}
+	}
+	if len(fields) > 0 {
+		slices.SortFunc(fields, func(a, b dto.FieldError) int { return strings.Compare(a.Field, b.Field) })
+		invalidFields(c, fields...)
+		return false
+	}
+
+	// Step 2: Decode and validate the values
+	if err := json.Unmarshal(body, target); err != nil {
+		bindingError(c, err)
+		return false
+	}
+	if err := binding.Validator.ValidateStruct(target); err != nil {
+		bindingError(c, err)
+		return false
+	}
+	return true
+}
+
+// invalidRequest reports a request that is wrong as a whole, answered as 400 by middlewares.ErrorHandler
+func invalidRequest(c *gin.Context, message string) {
+	c.Error(services.NewError(services.ErrValidation, "invalid_request", message))
+}
+
+// invalidField reports one invalid field, typically a path parameter
+func invalidField(c *gin.Context, field, rule, message string) {
+	invalidFields(c, dto.FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// invalidFields reports invalid fields, listed in the errors[] of the 400 response
+func invalidFields(c *gin.Context, fields ...dto.FieldError) {
+	err := services.NewError(services.ErrValidation, "invalid_request", "The request has invalid fields")
+	err.Fields = fields
+	c.Error(err)
+}
+
+// bindingError reports a failed ShouldBindJSON / ShouldBindQuery with one entry per invalid field
+func bindingError(c *gin.Context, err error) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]dto.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, dto.FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: ruleMessage(fe)})
+		}
+		invalidFields(c, fields...)
+	case errors.As(err, &typeErr):
+		invalidFields(c, dto.FieldError{Field: typeErr.Field, Rule: "type", Message: "must be a " + typeErr.Type.String()})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		invalidRequest(c, "The request body is not valid JSON")
+	default:
+		invalidRequest(c, err.Error())
+	}
+}
+
+// fieldPath returns the field's path below the request struct, e.g. "scopes[0]"
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// ruleMessage explains a failed validation rule in words
+func ruleMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "isdefault":
+		return "can't be set"
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
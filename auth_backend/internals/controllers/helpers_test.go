@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// register posts body to RegisterUser behind ErrorHandler and returns the status and problem
+func register(t *testing.T, body string) (int, dto.Problem) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.POST("/register", (&UserController{}).RegisterUser)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body)))
+
+	var problem dto.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	return w.Code, problem
+}
+
+// TestBindingErrorListsFields checks that validator failures come back per field, under their JSON names
+func TestBindingErrorListsFields(t *testing.T) {
+	code, problem := register(t, `{"name": "Ann", "age": 200}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_request", problem.Code)
+	assert.ElementsMatch(t, []dto.FieldError{
+		{Field: "email", Rule: "required", Message: "is required"},
+		{Field: "password", Rule: "required", Message: "is required"},
+		{Field: "age", Rule: "lte", Message: "must be at most 120"},
+	}, problem.Errors)
+
+	// Wrong JSON types and broken JSON
+	_, problem = register(t, `{"name": "Ann", "email": "ann@example.com", "password": "secret-pass", "age": "ten"}`)
+	assert.Equal(t, []dto.FieldError{{Field: "age", Rule: "type", Message: "must be a int"}}, problem.Errors)
+	code, problem = register(t, `{"name": `)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "The request body is not valid JSON", problem.Detail)
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginHistoryController serves the login history of the logged in user
+type LoginHistoryController struct {
+	historyService services.LoginHistoryService
+}
+
+// NewLoginHistoryController returns a new controller with injected service
+func NewLoginHistoryController(service services.LoginHistoryService) *LoginHistoryController {
+	return &LoginHistoryController{
+		historyService: service,
+	}
+}
+
+// ListLogins handles GET /users/me/logins
+func (hc *LoginHistoryController) ListLogins(c *gin.Context) {
+	var query dto.PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	page, err := hc.historyService.ListLoginsService(c.Request.Context(), c.GetUint("user_id"), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
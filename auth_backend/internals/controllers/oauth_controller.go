@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthController serves the token introspection and revocation endpoints
+type OAuthController struct {
+	oauthService services.OAuthService
+}
+
+// NewOAuthController returns a new controller with injected service
+func NewOAuthController(service services.OAuthService) *OAuthController {
+	return &OAuthController{
+		oauthService: service,
+	}
+}
+
+// IntrospectToken handles POST /oauth/introspect (RFC 7662)
+func (oc *OAuthController) IntrospectToken(c *gin.Context) {
+	if !oc.authenticateClient(c) {
+		return
+	}
+
+	var req dto.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	resp, err := oc.oauthService.IntrospectTokenService(c.Request.Context(), req.Token)
+	if err != nil {
+		oauthServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeToken handles POST /oauth/revoke (RFC 7009)
+func (oc *OAuthController) RevokeToken(c *gin.Context) {
+	if !oc.authenticateClient(c) {
+		return
+	}
+
+	var req dto.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	if err := oc.oauthService.RevokeTokenService(c.Request.Context(), req.Token); err != nil {
+		oauthServerError(c, err)
+		return
+	}
+
+	// RFC 7009: respond 200 whether or not the token was valid
+	c.Status(http.StatusOK)
+}
+
+// oauthServerError answers a failed revocation store lookup with 503, which RFC 7009 asks for so
+// the client retries the revocation; introspection answers the same so clients handle one status
+func oauthServerError(c *gin.Context, err error) {
+	log.Printf("oauth %s failed: %v", c.FullPath(), err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server_error"})
+}
+
+// authenticateClient accepts client_secret_basic and client_secret_post credentials
+func (oc *OAuthController) authenticateClient(c *gin.Context) bool {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	if err := oc.oauthService.AuthenticateClient(clientID, clientSecret); err != nil {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return false
+	}
+	return true
+}
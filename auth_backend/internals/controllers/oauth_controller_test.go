@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenStore is a revocation store failing with err, if set
+type tokenStore struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (s *tokenStore) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.revoked[token.JTI] = true
+	return nil
+}
+
+func (s *tokenStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.revoked[jti], s.err
+}
+
+// TestOAuthEndpoints checks client authentication, the answers to unknown tokens and store failures
+func TestOAuthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &tokenStore{revoked: map[string]bool{}}
+	oc := NewOAuthController(services.NewOAuthService(store, nil, map[string]string{"api": "s3cret"})) // the tokens have no session
+	r := gin.New()
+	r.POST("/oauth/introspect", oc.IntrospectToken)
+	r.POST("/oauth/revoke", oc.RevokeToken)
+
+	send := func(path string, form url.Values, basic ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if len(basic) == 2 {
+			req.SetBasicAuth(basic[0], basic[1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	token, err := utils.GenerateJWT(2, "ann@example.com", "user", "")
+	require.NoError(t, err)
+
+	// Bad client credentials, sent either way, are refused
+	for _, path := range []string{"/oauth/introspect", "/oauth/revoke"} {
+		w := send(path, url.Values{"token": {token}}, "api", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"error": "invalid_client"}`, w.Body.String())
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+		w = send(path, url.Values{"token": {token}, "client_id": {"other"}, "client_secret": {"s3cret"}})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, http.StatusBadRequest, send(path, url.Values{}, "api", "s3cret").Code)
+	}
+
+	w := send("/oauth/introspect", url.Values{"token": {token}, "client_id": {"api"}, "client_secret": {"s3cret"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":true`)
+
+	// Revoking an unknown token succeeds too (RFC 7009), a known one makes it inactive
+	assert.Equal(t, http.StatusOK, send("/oauth/revoke", url.Values{"token": {"unknown"}}, "api", "s3cret").Code)
+	assert.Equal(t, http.StatusOK, send("/oauth/revoke", url.Values{"token": {token}}, "api", "s3cret").Code)
+	w = send("/oauth/introspect", url.Values{"token": {token}}, "api", "s3cret")
+	assert.JSONEq(t, `{"active": false}`, w.Body.String())
+
+	// A failing store is answered the same by both endpoints
+	store.err = errors.New("connection refused")
+	for _, path := range []string{"/oauth/introspect", "/oauth/revoke"} {
+		w := send(path, url.Values{"token": {token}}, "api", "s3cret")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+		assert.JSONEq(t, `{"error": "server_error"}`, w.Body.String())
+	}
+}
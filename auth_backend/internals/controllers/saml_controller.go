@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+const samlRequestCookie = "saml_request_id"
+
+// SAMLController serves the SAML 2.0 service provider endpoints
+type SAMLController struct {
+	samlService services.SAMLService
+}
+
+// NewSAMLController returns a new controller with injected service
+func NewSAMLController(service services.SAMLService) *SAMLController {
+	return &SAMLController{
+		samlService: service,
+	}
+}
+
+// Metadata returns the SP metadata to register at the IdP
+func (sc *SAMLController) Metadata(c *gin.Context) {
+	metadata, err := sc.samlService.MetadataService()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// Login redirects the browser to the IdP with a new AuthnRequest
+func (sc *SAMLController) Login(c *gin.Context) {
+	redirectURL, requestID, err := sc.samlService.BeginLoginService(c.Query("relay_state"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The IdP posts back cross-site, so the cookie needs SameSite=None (and therefore Secure)
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(samlRequestCookie, requestID, 600, "/", "", true, true)
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// ACS is the assertion consumer service: it validates the posted assertion and logs the user in
+func (sc *SAMLController) ACS(c *gin.Context) {
+	var possibleRequestIDs []string
+	if requestID, err := c.Cookie(samlRequestCookie); err == nil && requestID != "" {
+		possibleRequestIDs = append(possibleRequestIDs, requestID)
+	}
+
+	resp, token, err := sc.samlService.CompleteLoginService(c.Request.Context(), c.Request, possibleRequestIDs, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(samlRequestCookie, "", -1, "/", "", true, true)
+
+	// Same session cookie as LoginUser
+	c.SetSameSite(http.SameSiteDefaultMode)
+	c.SetCookie("auth_token", token, 3600*24, "/", "localhost", false, true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "login successful",
+		"data":    resp,
+	})
+}
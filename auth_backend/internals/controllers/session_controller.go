@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController handles the session endpoints of the logged in user
+type SessionController struct {
+	sessionService services.SessionService
+}
+
+// NewSessionController returns a new controller with injected service
+func NewSessionController(service services.SessionService) *SessionController {
+	return &SessionController{
+		sessionService: service,
+	}
+}
+
+// ListSessions handles GET /users/me/sessions
+func (sc *SessionController) ListSessions(c *gin.Context) {
+	sessions, err := sc.sessionService.ListSessionsService(c.Request.Context(), c.GetUint("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /users/me/sessions/:session_id (remote sign-out of one device)
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	if err := sc.sessionService.RevokeSessionService(c.Request.Context(), c.GetUint("user_id"), c.Param("session_id")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session signed out"})
+}
+
+// RevokeOtherSessions handles DELETE /users/me/sessions ("sign out everywhere else")
+func (sc *SessionController) RevokeOtherSessions(c *gin.Context) {
+	count, err := sc.sessionService.RevokeOtherSessionsService(c.Request.Context(), c.GetUint("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "signed out everywhere else", "revoked": count})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/devesh121/userAuth/internals/dto"
 	"github.com/devesh121/userAuth/internals/services"
@@ -27,14 +28,14 @@ func (uc *UserController) RegisterUser(c *gin.Context) {
 
 	// Step 1: Bind JSON body to DTO and validate
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Please provide all required fields: name, email, and password "})
+		bindingError(c, err)
 		return
 	}
 
 	// Step 2: Call the service layer to register the user
-	user, err := uc.userService.RegisterUserService(req)
+	user, err := uc.userService.RegisterUserService(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -51,20 +52,25 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 
 	// Bind JSON
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request fields"})
+		bindingError(c, err)
 		return
 	}
 
 	// Call service
-	resp, token, err := uc.userService.LoginUserService(req)
+	resp, token, err := uc.userService.LoginUserService(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	//  set token on cookie
 	c.SetCookie("auth_token", token, 3600*24, "/", "localhost", false, true)
 
+	// Clients that can't keep cookies send the token as Authorization: Bearer
+	if req.ReturnToken {
+		resp.Token = token
+	}
+
 	// Return token
 	c.JSON(http.StatusOK, gin.H{
 		"message": "login successful",
@@ -76,21 +82,53 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 func (uc *UserController) LogoutUser(c *gin.Context) {
 	err := uc.userService.LogoutUserService(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "logout successful"})
 }
 
-// GetAllUser handles incoming getallusers request from client
+// GetAllUsers handles GET /users: one page of users, filtered and sorted by the query parameters
 func (uc *UserController) GetAllUsers(c *gin.Context) {
-	users, err := uc.userService.GetAllUsersService()
+	var query dto.UserListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	page, err := uc.userService.ListUsersService(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// RFC 8288 links to the neighbouring pages, keeping the filters and sort
+	links := []string{pageLink(c, "first", nil)}
+	if page.NextCursor != "" {
+		links = append(links, pageLink(c, "next", map[string]string{"cursor": page.NextCursor}))
+	}
+	if query.Offset > 0 {
+		links = append(links, pageLink(c, "prev", map[string]string{"offset": strconv.Itoa(max(query.Offset-page.Limit, 0))}))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchUsers handles GET /users/search?q=: users by partial name or email, best match first
+func (uc *UserController) SearchUsers(c *gin.Context) {
+	var query dto.UserSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	result, err := uc.userService.SearchUsersService(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, result)
 }
 
 // GetUserByID handles incoming GetUserByID request from client
@@ -98,16 +136,23 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil || id < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		invalidField(c, "id", "min", "must be a positive integer")
 		return
 	}
 
-	user, err := uc.userService.GetUserByIDService(uint(id))
+	user, err := uc.userService.GetUserByIDService(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	// The version is the ETag: unchanged since the client's copy means 304 without a body
+	etag := versionETag(user.Version)
+	c.Header("ETag", etag)
+	if noneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	c.JSON(http.StatusOK, user)
 }
 
@@ -117,13 +162,13 @@ func (uc *UserController) GetUserByEmail(c *gin.Context) {
 
 	// Bind JSON and validate
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request email ID format"})
+		bindingError(c, err)
 		return
 	}
 
-	user, err := uc.userService.GetUserByEmailService(req.Email)
+	user, err := uc.userService.GetUserByEmailService(c.Request.Context(), req.Email)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -135,24 +180,59 @@ func (uc *UserController) UpdateUserByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam) // converting string id into integer
 	if err != nil || id < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		invalidField(c, "id", "min", "must be a positive integer")
+		return
+	}
+
+	// If-Match names the version the client edited, so concurrent edits don't overwrite each other
+	version, ok := ifMatchVersion(c)
+	if !ok {
 		return
 	}
 
 	// request body ko dto.UpdateRequest me bind kiya ja raha hai
 	var req dto.UpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		bindingError(c, err)
 		return
 	}
 
 	// calling updateUser service layer and passing userid as unsigned int with updateUser data in dto form.
-	updatedUser, err := uc.userService.UpdateUserService(req, uint(id))
+	updatedUser, err := uc.userService.UpdateUserService(c.Request.Context(), req, uint(id), version, actor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", versionETag(updatedUser.Version))
+	c.JSON(http.StatusOK, updatedUser)
+}
+
+// PatchUserByID handles PATCH /users/:id with a JSON merge patch: only the fields sent change
+func (uc *UserController) PatchUserByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		invalidField(c, "id", "min", "must be a positive integer")
+		return
+	}
+
+	// Like PUT, the patch applies to the version named in If-Match
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	var patch dto.UserPatch
+	if !bindMergePatch(c, &patch) {
+		return
+	}
+
+	updatedUser, err := uc.userService.PatchUserService(c.Request.Context(), patch, uint(id), version, actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(updatedUser.Version))
 	c.JSON(http.StatusOK, updatedUser)
 }
 
@@ -161,19 +241,47 @@ func (uc *UserController) DeleteUserByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil || id < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		invalidField(c, "id", "min", "must be a positive integer")
 		return
 	}
 
-	err = uc.userService.DeleteUserService(uint(id))
+	err = uc.userService.DeleteUserService(c.Request.Context(), uint(id), actor(c))
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// ListDeletedUsers handles GET /admin/users/deleted: one page of the users that can be restored
+func (uc *UserController) ListDeletedUsers(c *gin.Context) {
+	var query dto.DeletedUserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	page, err := uc.userService.ListDeletedUsersService(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// RestoreUser handles POST /admin/users/:id/restore: undoes the deletion of a user
+func (uc *UserController) RestoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		invalidField(c, "id", "min", "must be a positive integer")
+		return
+	}
+
+	user, err := uc.userService.RestoreUserService(c.Request.Context(), uint(id), actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/middlewares"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedUserService serves one user and applies updates made from its current version
+type versionedUserService struct {
+	services.UserService
+	user dto.UserResponse
+}
+
+func (s *versionedUserService) GetUserByIDService(ctx context.Context, id uint) (*dto.UserResponse, error) {
+	user := s.user
+	return &user, nil
+}
+
+func (s *versionedUserService) UpdateUserService(ctx context.Context, req dto.UpdateRequest, id, version uint, actor dto.Actor) (*dto.UserResponse, error) {
+	if version != 0 && version != s.user.Version {
+		return nil, services.ErrVersionMismatch
+	}
+	s.user.Name = req.Name
+	s.user.Version++
+	user := s.user
+	return &user, nil
+}
+
+func (s *versionedUserService) PatchUserService(ctx context.Context, patch dto.UserPatch, id, version uint, actor dto.Actor) (*dto.UserResponse, error) {
+	if patch.Name != nil {
+		s.user.Name = *patch.Name
+	}
+	if patch.Age != nil {
+		s.user.Age = *patch.Age
+	}
+	s.user.Version++
+	user := s.user
+	return &user, nil
+}
+
+func (s *versionedUserService) DeleteUserService(ctx context.Context, id uint, actor dto.Actor) error {
+	return nil
+}
+
+// TestUserETags checks the ETag of GET /users/:id, 304 on If-None-Match and the If-Match rules of PUT
+func TestUserETags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	uc := NewUserController(&versionedUserService{user: dto.UserResponse{ID: 1, Name: "Ann", Version: 3}})
+	r.GET("/users/:id", uc.GetUserByID)
+	r.PUT("/users/:id", uc.UpdateUserByID)
+
+	send := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/users/1", strings.NewReader(`{"name": "Ann B", "email": "ann@example.com", "age": 30}`))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	w = send(http.MethodGet, map[string]string{"If-None-Match": `"2", W/"3"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	assert.Equal(t, http.StatusPreconditionRequired, send(http.MethodPut, nil).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPut, map[string]string{"If-Match": `"2"`}).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPut, map[string]string{"If-Match": `W/"3"`}).Code)
+	w = send(http.MethodPut, map[string]string{"If-Match": `"3"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	// The old ETag is stale now, the copy isn't fresh anymore
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPut, map[string]string{"If-Match": `"3"`}).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, map[string]string{"If-None-Match": `"3"`}).Code)
+}
+
+// TestPatchUserMergePatch checks the media type and the checks of a merge patch before it reaches the service
+func TestPatchUserMergePatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.PATCH("/users/:id", NewUserController(&versionedUserService{user: dto.UserResponse{ID: 1, Name: "Ann", Age: 30, Version: 1}}).PatchUserByID)
+
+	patch := func(contentType, body string) (int, dto.Problem, dto.UserResponse) {
+		req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var problem dto.Problem
+		var user dto.UserResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+		} else {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		}
+		return w.Code, problem, user
+	}
+
+	code, problem, _ := patch("text/plain", `{"name": "Ann B"}`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+	assert.Equal(t, "unsupported_media_type", problem.Code)
+
+	code, problem, _ = patch(mergePatchType, `{"name": null, "role": "admin", "age": 30}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []dto.FieldError{
+		{Field: "name", Rule: "nonnull", Message: "can't be removed"},
+		{Field: "role", Rule: "unknown", Message: "can't be changed"},
+	}, problem.Errors)
+
+	_, problem, _ = patch(mergePatchType, `{"email": "nope", "age": 200}`)
+	assert.ElementsMatch(t, []dto.FieldError{
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "age", Rule: "lte", Message: "must be at most 120"},
+	}, problem.Errors)
+	_, problem, _ = patch(mergePatchType, `["name"]`)
+	assert.Equal(t, "The patch must be a JSON object", problem.Detail)
+
+	// Absent fields are kept, zero values are set
+	code, _, user := patch(mergePatchType+"; charset=utf-8", `{"age": 0}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Ann", user.Name)
+	assert.Equal(t, 0, user.Age)
+}
+
+// TestRegisterRefusesRole checks that a sign-up can't pick its own role
+func TestRegisterRefusesRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.POST("/users/register", NewUserController(&versionedUserService{}).RegisterUser)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/register", strings.NewReader(`{"name": "Eve", "email": "eve@example.com", "password": "secret-pass", "age": 30, "role": "admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var problem dto.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, []dto.FieldError{{Field: "role", Rule: "isdefault", Message: "can't be set"}}, problem.Errors)
+}
+
+// TestWriteUserOwnerOnly checks that a user can't replace, patch or delete another user's account,
+// and an admin can
+func TestWriteUserOwnerOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	requests := []struct {
+		method, contentType, body string
+	}{
+		{http.MethodPut, "application/json", `{"name": "Bob", "email": "ann@example.com", "age": 30, "password": "taken-over"}`},
+		{http.MethodPatch, mergePatchType, `{"password": "taken-over"}`},
+		{http.MethodDelete, "", ""},
+	}
+	for _, tc := range requests {
+		sendAs := func(userID uint, role string) (int, dto.Problem) {
+			caller := func(c *gin.Context) {
+				c.Set("user_id", userID)
+				c.Set("user_role", role)
+			}
+			uc := NewUserController(&versionedUserService{user: dto.UserResponse{ID: 2, Name: "Bob", Version: 1}})
+			ownerOnly := middlewares.RequireSelfOrRole(nil, "id", "admin")
+			r := gin.New()
+			r.Use(middlewares.ErrorHandler())
+			r.PUT("/users/:id", caller, ownerOnly, uc.UpdateUserByID)
+			r.PATCH("/users/:id", caller, ownerOnly, uc.PatchUserByID)
+			r.DELETE("/users/:id", caller, ownerOnly, uc.DeleteUserByID)
+
+			req := httptest.NewRequest(tc.method, "/users/2", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			req.Header.Set("If-Match", "*")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			var problem dto.Problem
+			if w.Code != http.StatusOK {
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			}
+			return w.Code, problem
+		}
+
+		// Ann (1) writing Bob (2) is refused
+		code, problem := sendAs(1, "user")
+		assert.Equal(t, http.StatusForbidden, code, tc.method)
+		assert.Equal(t, "not_account_owner", problem.Code, tc.method)
+
+		code, _ = sendAs(2, "user")
+		assert.Equal(t, http.StatusOK, code, tc.method)
+		code, _ = sendAs(1, "admin")
+		assert.Equal(t, http.StatusOK, code, tc.method)
+	}
+}
+
+// TestUpdateUserPasswordRules checks that PUT /users/:id applies the password rules of PATCH
+func TestUpdateUserPasswordRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.PUT("/users/:id", NewUserController(&versionedUserService{user: dto.UserResponse{ID: 1, Version: 1}}).UpdateUserByID)
+
+	put := func(password string) (int, dto.Problem) {
+		body := `{"name": "Ann", "email": "ann@example.com", "age": 30, "password": "` + password + `"}`
+		req := httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var problem dto.Problem
+		if w.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		}
+		return w.Code, problem
+	}
+
+	code, problem := put("abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "password", problem.Errors[0].Field)
+	assert.Equal(t, "min", problem.Errors[0].Rule)
+
+	code, problem = put(strings.Repeat("a", 73))
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "max", problem.Errors[0].Rule)
+
+	code, _ = put("")
+	assert.Equal(t, http.StatusOK, code, "the password is optional")
+}
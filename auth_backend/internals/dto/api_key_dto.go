@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest is the payload of POST /users/me/api-keys
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"omitempty,dive,oneof=read write"`  // default: read
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,gte=1,lte=365"` // 0 = never expires
+}
+
+// APIKeyResponse describes a key without its secret
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+}
+
+// APIKeyCreatedResponse is returned once at creation, the only time the full key is visible
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
@@ -0,0 +1,69 @@
+package dto
+
+import "time"
+
+// Actor is who performs an action, recorded in the audit log
+type Actor struct {
+	ID    uint   // 0 when not logged in
+	Email string // email of the logged in user
+	ClientInfo
+}
+
+// AuditQuery holds the filters and paging of GET /admin/audit-events
+type AuditQuery struct {
+	EventType  string    `form:"event_type"`
+	ActorID    uint      `form:"actor_id"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	IP         string    `form:"ip"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339, inclusive
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // RFC 3339, exclusive
+	Limit      int       `form:"limit" binding:"omitempty,gte=1,lte=200"`      // default: 50
+	Offset     int       `form:"offset" binding:"omitempty,gte=0"`
+}
+
+// AuditEventResponse is one audit log entry
+type AuditEventResponse struct {
+	ID         uint           `json:"id"`
+	EventType  string         `json:"event_type"`
+	ActorID    *uint          `json:"actor_id"`
+	ActorEmail string         `json:"actor_email,omitempty"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   string         `json:"target_id,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	UserAgent  string         `json:"user_agent,omitempty"`
+	Metadata   map[string]any `json:"metadata"`
+	CreatedAt  time.Time      `json:"created_at"`
+	PrevHash   string         `json:"prev_hash"`
+	Hash       string         `json:"hash"`
+}
+
+// AuditEventPage is one page of audit events
+type AuditEventPage struct {
+	Events []AuditEventResponse `json:"events"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// AuditCheckpointResponse is a signed checkpoint of the audit chain
+type AuditCheckpointResponse struct {
+	ID        uint      `json:"id"`
+	EventID   uint      `json:"event_id"`
+	EventHash string    `json:"event_hash"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditVerifyResult is the outcome of walking the audit chain
+type AuditVerifyResult struct {
+	Valid                 bool   `json:"valid"`
+	EventsChecked         int    `json:"events_checked"`
+	CheckpointsChecked    int    `json:"checkpoints_checked"`
+	CheckpointsUnverified int    `json:"checkpoints_unverified,omitempty"` // no public key configured
+	FirstBrokenEventID    uint   `json:"first_broken_event_id,omitempty"`
+	FirstBrokenCheckpoint uint   `json:"first_broken_checkpoint_id,omitempty"`
+	Reason                string `json:"reason,omitempty"`
+	HeadHash              string `json:"head_hash,omitempty"`
+}
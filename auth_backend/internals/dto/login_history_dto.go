@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// PageQuery holds limit / offset paging parameters
+type PageQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,gte=1,lte=200"` // default: 50
+	Offset int `form:"offset" binding:"omitempty,gte=0"`
+}
+
+// LoginAttemptResponse is one entry of GET /users/me/logins
+type LoginAttemptResponse struct {
+	ID          uint      `json:"id"`
+	Success     bool      `json:"success"`
+	Reason      string    `json:"reason,omitempty"`
+	AuthMethod  string    `json:"auth_method,omitempty"`
+	IP          string    `json:"ip"`
+	Country     string    `json:"country,omitempty"`
+	UserAgent   string    `json:"user_agent"`
+	Fingerprint string    `json:"device_fingerprint"`
+	NewDevice   bool      `json:"new_device"`
+	NewNetwork  bool      `json:"new_network"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LoginHistoryPage is one page of login attempts
+type LoginHistoryPage struct {
+	Logins []LoginAttemptResponse `json:"logins"`
+	Total  int64                  `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+// TokenRequest is the form body of POST /oauth/introspect (RFC 7662) and POST /oauth/revoke (RFC 7009)
+type TokenRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"` // Optional, only access tokens are issued today
+}
+
+// IntrospectionResponse is the RFC 7662 introspection response.
+// For inactive tokens only "active": false is returned.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	UserID    uint   `json:"user_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+}
+
+// FederationState is kept in a short lived cookie between the redirect to the provider and the callback
+type FederationState struct {
+	State        string // CSRF protection, echoed back by the provider
+	Nonce        string // bound into the OIDC id_token
+	CodeVerifier string // PKCE verifier
+}
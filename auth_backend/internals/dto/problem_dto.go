@@ -0,0 +1,20 @@
+package dto
+
+// Problem is an RFC 7807 problem details body (application/problem+json), the shape of every
+// error answered by the /api/v1 routes
+type Problem struct {
+	Type     string       `json:"type"`               // URI identifying the kind of problem, ends with Code
+	Title    string       `json:"title"`              // text of the HTTP status
+	Status   int          `json:"status"`             // HTTP status
+	Detail   string       `json:"detail,omitempty"`   // what went wrong with this request
+	Instance string       `json:"instance,omitempty"` // request ID, also in the X-Request-ID header
+	Code     string       `json:"code"`               // stable, machine readable error code
+	Errors   []FieldError `json:"errors,omitempty"`   // invalid fields of a 400 response
+}
+
+// FieldError is one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`   // JSON (or query) name of the field
+	Rule    string `json:"rule"`    // failed validation rule, e.g. "required", "email", "max"
+	Message string `json:"message"` // readable explanation
+}
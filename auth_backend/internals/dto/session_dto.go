@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// ClientInfo describes the client making a request; it is stored with sessions
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionResponse is one entry of GET /users/me/sessions
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	AuthMethod string    `json:"auth_method"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session of the calling token
+}
@@ -1,18 +1,21 @@
 package dto
 
+import "time"
+
 // 📝 Request struct for user registration
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`              // Required field
 	Email    string `json:"email" binding:"required"`             // Required + email format (can add custom validator)
 	Password string `json:"password" binding:"required"`          // Required field
 	Age      int    `json:"age" binding:"required,gte=5,lte=120"` // required
-	Role     string `json:"role"`                                 // Required field
+	Role     string `json:"role" binding:"isdefault"`             // Refused: roles come from admins or the identity provider, never from a sign-up
 }
 
 // 🔐 Login request payload
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required"`    // Required
-	Password string `json:"password" binding:"required"` // Required
+	Email       string `json:"email" binding:"required"`    // Required
+	Password    string `json:"password" binding:"required"` // Required
+	ReturnToken bool   `json:"return_token"`                // Optional: also return the JWT in the body (mobile / CLI clients)
 }
 
 // login response struct dto
@@ -27,11 +30,59 @@ type LoginResponse struct {
 
 // 📤 Response struct to return filtered user info (excluding sensitive data like password)
 type UserResponse struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Age   int    `json:"age"`
-	Role  string `json:"role"`
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`          // false until the link sent to the email is opened
+	PendingEmail  string    `json:"pending_email,omitempty"` // requested new email, used once confirmed
+	Age           int       `json:"age"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+	Version       uint      `json:"version"` // also the ETag of GET /users/:id, send it in If-Match to update
+}
+
+// UserListQuery holds the filters, sort and paging of GET /users. Page with either offset or cursor.
+type UserListQuery struct {
+	Role        string    `form:"role"`
+	MinAge      *int      `form:"min_age" binding:"omitempty,gte=0,lte=120"`
+	MaxAge      *int      `form:"max_age" binding:"omitempty,gte=0,lte=120"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`                                          // RFC 3339, inclusive
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`                                            // RFC 3339, exclusive
+	Email       string    `form:"email" binding:"omitempty,max=254"`                                                             // substring, case-insensitive
+	Name        string    `form:"name" binding:"omitempty,max=100"`                                                              // substring, case-insensitive
+	Sort        string    `form:"sort" binding:"omitempty,oneof=id -id name -name email -email age -age created_at -created_at"` // "-" for descending, default: id
+	Limit       int       `form:"limit" binding:"omitempty,gte=1,lte=200"`                                                       // default: 50
+	Offset      int       `form:"offset" binding:"omitempty,gte=0"`
+	Cursor      string    `form:"cursor"` // next_cursor of the previous page
+}
+
+// UserPage is one page of GET /users
+type UserPage struct {
+	Users      []UserResponse `json:"users"`
+	Total      int64          `json:"total"` // users matching the filters, on all pages
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// DeletedUserQuery holds the paging of GET /admin/users/deleted
+type DeletedUserQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,gte=1,lte=200"` // default: 50
+	Offset int `form:"offset" binding:"omitempty,gte=0"`
+}
+
+// DeletedUserResponse is a soft-deleted user, restorable until purged
+type DeletedUserResponse struct {
+	UserResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// DeletedUserPage is one page of GET /admin/users/deleted, most recently deleted first
+type DeletedUserPage struct {
+	Users  []DeletedUserResponse `json:"users"`
+	Total  int64                 `json:"total"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset,omitempty"`
 }
 
 // UpdateRequest defines the expected payload for updating a user
@@ -39,9 +90,49 @@ type UpdateRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required"`
 	Age      int    `json:"age" binding:"required,gte=0,lte=120"`
-	Password string `json:"password,omitempty"` // Optional
+	Password string `json:"password,omitempty" binding:"omitempty,min=6,max=72"` // Optional, bcrypt uses 72 bytes at most
+}
+
+// UserPatch is a JSON merge patch (RFC 7396) of PATCH /users/:id: fields that are present are
+// set, absent ones are kept. None of them can be removed, so null is rejected.
+type UserPatch struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Email    *string `json:"email" binding:"omitempty,email,max=254"`
+	Age      *int    `json:"age" binding:"omitempty,gte=0,lte=120"`
+	Password *string `json:"password" binding:"omitempty,min=6,max=72"` // bcrypt uses 72 bytes at most
+}
+
+// UserSearchQuery holds the parameters of GET /users/search
+type UserSearchQuery struct {
+	Q     string `form:"q" binding:"required,min=2,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,gte=1,lte=50"` // default: 20
+}
+
+// UserSearchHit is one result of GET /users/search
+type UserSearchHit struct {
+	UserResponse
+	Rank      float64       `json:"rank"` // relevance, higher is better; only comparable within one response
+	Highlight UserHighlight `json:"highlight"`
+}
+
+// UserHighlight repeats name and email HTML escaped, with the matched parts wrapped in <mark>
+type UserHighlight struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserSearchResult is the response of GET /users/search
+type UserSearchResult struct {
+	Query string          `json:"query"`
+	Users []UserSearchHit `json:"users"`
 }
 
 type GetUserByEmailRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
+
+// EmailTokenRequest carries the token of a mailed link to POST /users/verify-email, confirm-email
+// and revert-email, as JSON or as the form of the link's page
+type EmailTokenRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
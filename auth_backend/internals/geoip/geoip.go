@@ -0,0 +1,51 @@
+// Package geoip looks up the country of an IP address in a local MaxMind database file.
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Locator resolves IP addresses to ISO country codes
+type Locator interface {
+	// Country returns the ISO 3166-1 alpha-2 code, or "" when unknown
+	Country(ip string) string
+}
+
+// NewLocator opens a GeoLite2 / GeoIP2 Country or City database.
+// Without a path every lookup returns "".
+func NewLocator(path string) (Locator, error) {
+	if path == "" {
+		return noopLocator{}, nil
+	}
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbLocator{reader: reader}, nil
+}
+
+// mmdbLocator reads a MaxMind database
+type mmdbLocator struct {
+	reader *geoip2.Reader
+}
+
+// Country looks the address up in the database
+func (l *mmdbLocator) Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsLoopback() || parsed.IsPrivate() {
+		return ""
+	}
+	record, err := l.reader.Country(parsed)
+	if err != nil {
+		return ""
+	}
+	return record.Country.IsoCode
+}
+
+// noopLocator is used when no database is configured
+type noopLocator struct{}
+
+// Country always returns ""
+func (noopLocator) Country(string) string { return "" }
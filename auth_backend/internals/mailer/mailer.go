@@ -0,0 +1,73 @@
+// Package mailer sends transactional emails (security alerts, verification links, ...).
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/devesh121/userAuth/pkg/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by every mail backend
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailer builds the backend selected by MAILER: "smtp" or "log" (default, for development)
+func NewMailer(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Backend {
+	case "", "log":
+		return logMailer{}, nil
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("smtp mailer needs SMTP_HOST and MAIL_FROM")
+		}
+		return &smtpMailer{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", cfg.Backend)
+	}
+}
+
+// logMailer writes emails to the log instead of sending them
+type logMailer struct{}
+
+// Send logs the message
+func (logMailer) Send(msg Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// smtpMailer sends emails through an SMTP relay
+type smtpMailer struct {
+	cfg config.MailerConfig
+}
+
+// Send delivers the message, authenticating when a username is configured
+func (m *smtpMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// Header values must not carry line breaks
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	body := "From: " + m.cfg.From + "\r\n" +
+		"To: " + clean.Replace(msg.To) + "\r\n" +
+		"Subject: " + clean.Replace(msg.Subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + msg.Body
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	return smtp.SendMail(addr, auth, m.cfg.From, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// stubAPIKeys accepts the keys it holds and records their use
+type stubAPIKeys struct {
+	services.APIKeyService
+	keys map[string]*models.APIKey
+	used []string // IPs the keys were used from
+}
+
+func (s *stubAPIKeys) AuthenticateAPIKeyService(ctx context.Context, rawKey, ip string) (*models.User, *models.APIKey, error) {
+	key, ok := s.keys[rawKey]
+	if !ok {
+		return nil, nil, services.ErrAPIKeyInvalid
+	}
+	s.used = append(s.used, ip)
+	return &models.User{Model: gorm.Model{ID: key.UserID}, Email: "ann@example.com", Role: "user"}, key, nil
+}
+
+// TestAPIKeyAuth checks key lookup, the read only scope, and that key routes need a session
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &recordingAudit{}
+	apiKeys := &stubAPIKeys{keys: map[string]*models.APIKey{
+		"ak_read_secret":  {Model: gorm.Model{ID: 1}, UserID: 5, Scopes: "read"},
+		"ak_write_secret": {Model: gorm.Model{ID: 2}, UserID: 5, Scopes: "read write"},
+	}}
+	r := gin.New()
+	r.Use(APIKeyAuthMiddleware(apiKeys, audit), JWTAuthMiddleware(AuthConfig{TokenRepo: noRevocations{}, Audit: audit}))
+	var userID uint
+	ok := func(c *gin.Context) {
+		userID = c.GetUint("user_id")
+		c.Status(http.StatusOK)
+	}
+	r.GET("/users", ok)
+	r.POST("/users", ok)
+	r.POST("/me/api-keys", RequireSession(audit), ok)
+
+	send := func(method, path string, headers map[string]string) int {
+		userID = 0
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Both headers work and the use is recorded with the client IP
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/users", map[string]string{"X-API-Key": "ak_read_secret"}))
+	assert.Equal(t, uint(5), userID)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/users", map[string]string{"Authorization": "Bearer ak_read_secret"}))
+	assert.Equal(t, []string{"192.0.2.10", "192.0.2.10"}, apiKeys.used)
+
+	// Unknown keys are refused and audited
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/users", map[string]string{"X-API-Key": "ak_unknown"}))
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, services.EventAPIKeyRejected, audit.entries[0].Type)
+
+	// A read key may only read
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/users", map[string]string{"X-API-Key": "ak_read_secret"}))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/users", map[string]string{"X-API-Key": "ak_write_secret"}))
+
+	// Not even a write key can manage keys, a session can
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/me/api-keys", map[string]string{"X-API-Key": "ak_write_secret"}))
+	assert.Equal(t, services.EventAccessDenied, audit.entries[len(audit.entries)-1].Type)
+	token, err := utils.GenerateJWT(5, "ann@example.com", "user", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/me/api-keys", map[string]string{"Authorization": "Bearer " + token}))
+}
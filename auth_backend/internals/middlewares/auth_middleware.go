@@ -1,37 +1,151 @@
 package middlewares
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
 	"github.com/devesh121/userAuth/internals/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func JWTAuthMiddleware() gin.HandlerFunc {
+// AuthConfig configures JWTAuthMiddleware
+type AuthConfig struct {
+	TokenRepo   repositories.TokenRepo  // revocation store
+	Sessions    services.SessionService // rejects tokens of terminated sessions
+	Audit       services.AuditService   // records rejected tokens, optional
+	TokenLookup []string                // token sources tried in order: "header", "cookie", "query"
+}
+
+// Errors of JWTAuthMiddleware, answered through abortWithError
+var (
+	errTokenMissing = services.NewError(services.ErrUnauthorized, "token_missing", "Unauthorized: token not found")
+	errTokenInvalid = services.NewError(services.ErrUnauthorized, "token_invalid", "Unauthorized: invalid token")
+	errTokenRevoked = services.NewError(services.ErrUnauthorized, "token_revoked", "Unauthorized: token revoked")
+)
+
+// DefaultTokenLookup is used when AuthConfig.TokenLookup is empty
+var DefaultTokenLookup = []string{"header", "cookie"}
+
+func JWTAuthMiddleware(cfg AuthConfig) gin.HandlerFunc {
+	lookup := cfg.TokenLookup
+	if len(lookup) == 0 {
+		lookup = DefaultTokenLookup
+	}
+
 	return func(c *gin.Context) {
-		// Get token from cookie
-		token, err := c.Cookie("auth_token")
-		if err != nil || strings.TrimSpace(token) == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: token not found"})
-			c.Abort()
+		// Already authenticated by APIKeyAuthMiddleware
+		if _, ok := c.Get("user_id"); ok {
+			c.Next()
+			return
+		}
+
+		// Get token from the configured sources (Authorization header, cookie, query)
+		token := extractToken(c, lookup)
+		if token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			abortWithError(c, errTokenMissing)
 			return
 		}
 
 		// Validate token
 		claims, err := utils.ValidateJWT(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: invalid token"})
-			c.Abort()
+			recordRejection(c, cfg.Audit, services.AuditEntry{
+				Type:     services.EventTokenRejected,
+				Metadata: map[string]any{"reason": "invalid token"},
+			})
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			abortWithError(c, errTokenInvalid)
 			return
 		}
 
+		// Reject tokens that were revoked through /oauth/revoke
+		if claims.ID != "" {
+			revoked, err := cfg.TokenRepo.IsTokenRevoked(c.Request.Context(), claims.ID)
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			if revoked {
+				recordRejection(c, cfg.Audit, services.AuditEntry{
+					Type:     services.EventTokenRejected,
+					Actor:    dto.Actor{ID: claims.UserID, Email: claims.Email},
+					Metadata: map[string]any{"reason": "token revoked", "jti": claims.ID},
+				})
+				c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				abortWithError(c, errTokenRevoked)
+				return
+			}
+		}
+
+		// Reject tokens whose session was signed out (logout, remote sign-out)
+		if claims.SessionID != "" {
+			if err := cfg.Sessions.ValidateSessionService(c.Request.Context(), claims.SessionID); err != nil {
+				if errors.Is(err, services.ErrSessionTerminated) {
+					recordRejection(c, cfg.Audit, services.AuditEntry{
+						Type:       services.EventTokenRejected,
+						Actor:      dto.Actor{ID: claims.UserID, Email: claims.Email},
+						TargetType: "session",
+						TargetID:   claims.SessionID,
+						Metadata:   map[string]any{"reason": "session terminated"},
+					})
+					c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				}
+				abortWithError(c, err)
+				return
+			}
+		}
+
 		// we can set the user ID / email / role into context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
 
 		// Continue to handler
 		c.Next()
 	}
 }
+
+// recordRejection writes a refused request to the audit log, with the caller's client info
+func recordRejection(c *gin.Context, audit services.AuditService, entry services.AuditEntry) {
+	if audit == nil {
+		return
+	}
+	entry.Actor.IP = c.ClientIP()
+	entry.Actor.UserAgent = c.Request.UserAgent()
+	if entry.Metadata == nil {
+		entry.Metadata = map[string]any{}
+	}
+	entry.Metadata["method"] = c.Request.Method
+	entry.Metadata["path"] = c.Request.URL.Path
+	audit.Record(c.Request.Context(), entry)
+}
+
+// extractToken returns the first non empty token found in the given sources
+func extractToken(c *gin.Context, lookup []string) string {
+	for _, source := range lookup {
+		var token string
+		switch source {
+		case "header":
+			// Authorization: Bearer <token>, used by mobile apps and CLI clients
+			scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+			if ok && strings.EqualFold(scheme, "Bearer") {
+				token = value
+			}
+		case "cookie":
+			token, _ = c.Cookie("auth_token")
+		case "query":
+			// Browsers can't set headers on websocket upgrades, so allow ?access_token=
+			token = c.Query("access_token")
+		}
+
+		if token = strings.TrimSpace(token); token != "" {
+			return token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noRevocations is a TokenRepo where nothing is revoked
+type noRevocations struct{}
+
+func (noRevocations) RevokeToken(context.Context, *models.RevokedToken) error { return nil }
+func (noRevocations) IsTokenRevoked(context.Context, string) (bool, error)    { return false, nil }
+
+// runAuth sends req through JWTAuthMiddleware and returns the status and the user ID set in the context
+func runAuth(t *testing.T, lookup []string, req *http.Request) (int, uint) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var userID uint
+	r.GET("/", JWTAuthMiddleware(AuthConfig{TokenRepo: noRevocations{}, TokenLookup: lookup}), func(c *gin.Context) {
+		userID = c.GetUint("user_id")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, userID
+}
+
+// TestJWTAuthTokenSources checks the header, cookie and query sources and their order
+func TestJWTAuthTokenSources(t *testing.T) {
+	headerToken, err := utils.GenerateJWT(1, "header@example.com", "user", "")
+	require.NoError(t, err)
+	cookieToken, err := utils.GenerateJWT(2, "cookie@example.com", "user", "")
+	require.NoError(t, err)
+
+	// Bearer header
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+headerToken)
+	code, id := runAuth(t, nil, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, uint(1), id)
+
+	// Header wins over cookie with the default order, cookie wins when configured first
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+headerToken)
+	req.AddCookie(&http.Cookie{Name: "auth_token", Value: cookieToken})
+	_, id = runAuth(t, nil, req)
+	assert.Equal(t, uint(1), id)
+	_, id = runAuth(t, []string{"cookie", "header"}, req)
+	assert.Equal(t, uint(2), id)
+
+	// Query is only accepted when enabled
+	req = httptest.NewRequest(http.MethodGet, "/?access_token="+headerToken, nil)
+	code, _ = runAuth(t, nil, req)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, id = runAuth(t, []string{"header", "query"}, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, uint(1), id)
+}
+
+// TestJWTAuthRejectsInvalidToken checks missing, malformed and non-bearer credentials
+func TestJWTAuthRejectsInvalidToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	code, _ := runAuth(t, nil, req)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	code, _ = runAuth(t, nil, req)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	code, _ = runAuth(t, nil, req)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+// recordingAudit keeps the recorded entries in memory; other methods aren't used by the middlewares
+type recordingAudit struct {
+	services.AuditService
+	entries []services.AuditEntry
+}
+
+func (a *recordingAudit) Record(_ context.Context, entry services.AuditEntry) {
+	a.entries = append(a.entries, entry)
+}
+
+// TestRequireRoleAuditsDenials checks that non admins are refused and the refusal is audited
+func TestRequireRoleAuditsDenials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &recordingAudit{}
+	r := gin.New()
+	r.GET("/admin", JWTAuthMiddleware(AuthConfig{TokenRepo: noRevocations{}, Audit: audit}), RequireRole(audit, "admin"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(role string) int {
+		token, err := utils.GenerateJWT(5, role+"@example.com", role, "")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("admin"))
+	assert.Equal(t, http.StatusForbidden, send("user"))
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, services.EventAccessDenied, audit.entries[0].Type)
+	assert.Equal(t, uint(5), audit.entries[0].Actor.ID)
+	assert.Equal(t, "/admin", audit.entries[0].Metadata["path"])
+
+	// Bad tokens are audited as well
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	require.Len(t, audit.entries, 2)
+	assert.Equal(t, services.EventTokenRejected, audit.entries[1].Type)
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// kindStatus maps the kinds of services.Error to their HTTP status
+var kindStatus = []struct {
+	kind   error
+	status int
+}{
+	{services.ErrNotFound, http.StatusNotFound},
+	{services.ErrConflict, http.StatusConflict},
+	{services.ErrValidation, http.StatusBadRequest},
+	{services.ErrUnauthorized, http.StatusUnauthorized},
+	{services.ErrForbidden, http.StatusForbidden},
+	{services.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{services.ErrPreconditionRequired, http.StatusPreconditionRequired},
+	{services.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType},
+}
+
+// ProblemTypeBase prefixes the error code in the "type" URI of problem responses
+var ProblemTypeBase = "/problems/"
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// ErrorHandler answers requests whose handler reported an error with c.Error and wrote nothing,
+// as application/problem+json (dto.Problem). See problemFor for the mapping.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+		problem := problemFor(c, last.Err)
+		c.Header("Content-Type", problemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// abortWithError answers err the same way ErrorHandler does and stops the chain
+func abortWithError(c *gin.Context, err error) {
+	problem := problemFor(c, err)
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// problemFor maps an error to its problem details: database timeouts to 504, an unreachable
+// database to 503, services.Error by its kind, anything else to a 500 that hides the details.
+func problemFor(c *gin.Context, err error) dto.Problem {
+	status, code, detail := http.StatusInternalServerError, "internal_error", "internal server error"
+	var fields []dto.FieldError
+
+	var domainErr *services.Error
+	switch {
+	case repositories.IsTimeout(err) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded):
+		status, code, detail = http.StatusGatewayTimeout, "database_timeout", "database timeout"
+	case repositories.IsUnavailable(err):
+		status, code, detail = http.StatusServiceUnavailable, "database_unavailable", "database unavailable"
+	case errors.As(err, &domainErr):
+		for _, ks := range kindStatus {
+			if errors.Is(domainErr.Kind, ks.kind) {
+				status, code, detail, fields = ks.status, domainErr.Code, domainErr.Message, domainErr.Fields
+				break
+			}
+		}
+	}
+
+	requestID := utils.RequestID(c.Request.Context())
+	if status == http.StatusInternalServerError {
+		log.Printf("request %s: %s %s failed: %v", requestID, c.Request.Method, c.Request.URL.Path, err)
+	}
+	return dto.Problem{
+		Type:     ProblemTypeBase + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: requestID,
+		Code:     code,
+		Errors:   fields,
+	}
+}
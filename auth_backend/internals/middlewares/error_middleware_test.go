@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestErrorHandlerMapsKinds checks the problem answered for each kind of error
+func TestErrorHandlerMapsKinds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{services.ErrUserNotExist.Wrap(gorm.ErrRecordNotFound), http.StatusNotFound, "user_not_found"},
+		{fmt.Errorf("register: %w", services.ErrEmailTaken), http.StatusConflict, "email_taken"},
+		{services.NewError(services.ErrValidation, "invalid_request", "Invalid input"), http.StatusBadRequest, "invalid_request"},
+		{services.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+		{services.ErrSessionLimitReached, http.StatusForbidden, "session_limit_reached"},
+		{errors.New("failed to hash password"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		r := gin.New()
+		r.Use(RequestID(), ErrorHandler())
+		r.GET("/", func(c *gin.Context) { c.Error(tt.err) })
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var problem dto.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, tt.code, problem.Code)
+		assert.Equal(t, "/problems/"+tt.code, problem.Type)
+		assert.Equal(t, tt.status, problem.Status)
+		assert.Equal(t, "req-1", problem.Instance)
+	}
+}
+
+// TestErrorHandlerKeepsWrittenResponse checks that a handler's own response isn't replaced
+func TestErrorHandlerKeepsWrittenResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.JSON(http.StatusAccepted, gin.H{"message": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"ok"}`, w.Body.String())
+}
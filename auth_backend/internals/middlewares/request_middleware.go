@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID accepts IDs from proxies and clients that are safe to log and echo
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID gives every request an ID: the caller's X-Request-ID if it looks sane, else a new one.
+// The ID is echoed in the response and carried in the request context (utils.RequestID), where
+// the audit log picks it up.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id, _ = utils.RandomString(12)
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// DBTimeout bounds the database work of a request: the request context gets a deadline of d and
+// every query runs under it, so queries still running then are cancelled and the handler answers
+// 504. A client that disconnects cancels its queries too. d <= 0 disables the deadline.
+func DBTimeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
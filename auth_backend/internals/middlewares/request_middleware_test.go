@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestID checks that a sane incoming ID is kept and anything else replaced
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var seen string
+	r.GET("/", RequestID(), func(c *gin.Context) {
+		seen = utils.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "edge-7f3a")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "edge-7f3a", seen)
+	assert.Equal(t, "edge-7f3a", w.Header().Get(RequestIDHeader))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\nwith newline", seen)
+	assert.NotEmpty(t, seen)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
+
+// slowRevocations is a TokenRepo whose lookups hang until the query is cancelled
+type slowRevocations struct{}
+
+func (slowRevocations) RevokeToken(context.Context, *models.RevokedToken) error { return nil }
+func (slowRevocations) IsTokenRevoked(ctx context.Context, _ string) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+// TestDBTimeoutAnswers504 checks that a query running past DB_TIMEOUT is cancelled and reported as 504
+func TestDBTimeoutAnswers504(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, err := utils.GenerateJWT(1, "ann@example.com", "user", "")
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.GET("/", DBTimeout(20*time.Millisecond), JWTAuthMiddleware(AuthConfig{TokenRepo: slowRevocations{}}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a personal API key used by scripts and CI instead of the cookie login.
+// Only the SHA-256 hash of the key is stored; Prefix is kept in clear so users can tell keys apart.
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"index;not null"`
+	Name       string     `gorm:"size:100;not null"`
+	Prefix     string     `gorm:"size:16;index;not null"`       // e.g. "ak_3f9a1c2b"
+	KeyHash    string     `gorm:"uniqueIndex;size:64;not null"` // hex SHA-256 of the full key
+	Scopes     string     // space separated, "read" and/or "write"
+	ExpiresAt  *time.Time // nil = never expires
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"size:45"`
+}
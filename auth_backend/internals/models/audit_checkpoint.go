@@ -0,0 +1,23 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditCheckpoint is a signed statement that the audit chain ended with EventHash at EventID.
+// Even someone able to rewrite the whole chain can't produce checkpoints without the signing key.
+type AuditCheckpoint struct {
+	ID        uint      `gorm:"primaryKey"`
+	EventID   uint      `gorm:"index;not null"`   // last event covered
+	EventHash string    `gorm:"size:64;not null"` // Hash of that event
+	KeyID     string    `gorm:"size:16"`          // first bytes of the public key, hex
+	Signature string    `gorm:"not null"`         // base64 Ed25519 signature of SignedPayload
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// SignedPayload returns the bytes covered by the signature
+func (c *AuditCheckpoint) SignedPayload() []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint:%d:%s:%s",
+		c.EventID, c.EventHash, c.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)))
+}
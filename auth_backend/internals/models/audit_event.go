@@ -0,0 +1,82 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// AuditEvent is one entry of the append-only audit log.
+// Rows are only ever inserted: the repository has no update or delete and
+// a database trigger rejects UPDATE / DELETE on the table.
+// Each event is chained to the previous one by hash, so edits and removals are detectable.
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey"`
+	EventType  string    `gorm:"size:64;index;not null"` // e.g. "user.registered", see services.Event*
+	ActorID    *uint     `gorm:"index"`                  // nil for anonymous callers (failed login, bad token)
+	ActorEmail string    `gorm:"size:255"`
+	TargetType string    `gorm:"size:32;index:idx_audit_target"` // e.g. "user", "session"
+	TargetID   string    `gorm:"size:64;index:idx_audit_target"`
+	IP         string    `gorm:"size:45"`
+	UserAgent  string    `gorm:"size:512"`
+	Metadata   JSONMap   `gorm:"type:jsonb"`
+	CreatedAt  time.Time `gorm:"index;not null"`
+	PrevHash   string    `gorm:"size:64;uniqueIndex"` // Hash of the previous event, "" for the first one; unique so the chain can't fork
+	Hash       string    `gorm:"size:64;not null"`    // ChainHash of this event
+}
+
+// ChainHash returns the hex SHA-256 over PrevHash and the content of the event.
+// The ID isn't covered (it is assigned by the database), the order is kept by PrevHash instead.
+func (e *AuditEvent) ChainHash() string {
+	metadata := e.Metadata
+	if metadata == nil {
+		metadata = JSONMap{} // stored as {}
+	}
+	// Fixed field order; timestamps at the precision the database keeps
+	payload, _ := json.Marshal([]any{
+		e.PrevHash,
+		e.EventType,
+		e.ActorID,
+		e.ActorEmail,
+		e.TargetType,
+		e.TargetID,
+		e.IP,
+		e.UserAgent,
+		metadata,
+		e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// JSONMap is a JSON object column
+type JSONMap map[string]any
+
+// Value stores the map as JSON text
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Scan reads the JSON column back into the map
+func (m *JSONMap) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported type for JSONMap")
+	}
+	return json.Unmarshal(b, m)
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// LinkedIdentity links an account at an upstream identity provider to a local user
+type LinkedIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"index;not null"`
+	Provider string `gorm:"uniqueIndex:idx_identity_provider_subject;size:64;not null"` // e.g. "google"
+	Subject  string `gorm:"uniqueIndex:idx_identity_provider_subject;not null"`         // user ID at the provider
+	Email    string // email reported by the provider at link time
+}
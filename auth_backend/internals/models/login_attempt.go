@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LoginAttempt is one entry of a user's login history, successful or not
+type LoginAttempt struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"index:idx_login_user_created;not null"`
+	Success     bool      `gorm:"not null"`
+	Reason      string    `gorm:"size:255"` // why the attempt failed
+	AuthMethod  string    `gorm:"size:32"`  // e.g. "local", "ldap", "saml", "google"
+	IP          string    `gorm:"size:45"`
+	Network     string    `gorm:"size:64;index"` // IPv4 /24 or IPv6 /48 of IP, e.g. "203.0.113.0/24"
+	Country     string    `gorm:"size:2"`        // ISO code from the GeoIP database, "" when unknown
+	UserAgent   string    `gorm:"size:512"`
+	Fingerprint string    `gorm:"size:16;index"` // hash of the user agent without version numbers
+	NewDevice   bool      // first successful login with this fingerprint
+	NewNetwork  bool      // first successful login from this network
+	CreatedAt   time.Time `gorm:"index:idx_login_user_created"`
+}
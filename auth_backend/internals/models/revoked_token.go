@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RevokedToken is one entry of the token revocation store.
+// Tokens are identified by their jti claim; rows can be cleaned up once ExpiresAt has passed.
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey"`
+	JTI       string    `gorm:"uniqueIndex;size:64;not null"` // token ID (jti claim)
+	UserID    uint      `gorm:"index"`                        // owner of the revoked token
+	ExpiresAt time.Time `gorm:"index"`                        // original expiry of the token
+	RevokedAt time.Time `gorm:"autoCreateTime"`
+}
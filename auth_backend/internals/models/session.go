@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one login of a user on a device. The token issued at login carries the session ID
+// (sid claim), so terminating the session invalidates the token.
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:64"`
+	UserID     uint       `gorm:"index;not null"`
+	AuthMethod string     `gorm:"size:32"` // local, ldap, saml, or the federation provider name
+	UserAgent  string     `gorm:"size:512"`
+	IP         string     `gorm:"size:45"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	LastSeenAt time.Time  // updated by JWTAuthMiddleware
+	ExpiresAt  time.Time  `gorm:"index"` // same as the token expiry
+	RevokedAt  *time.Time // set on logout / remote sign-out
+}
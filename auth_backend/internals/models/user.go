@@ -1,12 +1,21 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
-	gorm.Model        // automatically handles ID, CreatedAt, UpdatedAt
-	Name       string `json:"name" binding:"required"`                      // required
-	Email      string `json:"email" gorm:"unique" binding:"required,email"` // required + must be a valid email
-	Password   string `json:"password" binding:"required,min=6"`            // required + minimum 6 chars
-	Age        int    `json:"age" binding:"required,gte=0,lte=120"`         // required + between 0 and 100
-	Role       string `json:"role" gorm:"default:user"`                     // required
+	gorm.Model                 // automatically handles ID, CreatedAt, UpdatedAt
+	Name            string     `json:"name" binding:"required"`                                                                         // required
+	Email           string     `json:"email" gorm:"uniqueIndex:idx_users_email_live,where:deleted_at IS NULL" binding:"required,email"` // required + must be a valid email, unique among users that aren't deleted
+	Password        string     `json:"password" binding:"required,min=6"`                                                               // required + minimum 6 chars
+	Age             int        `json:"age" binding:"required,gte=0,lte=120"`                                                            // required + between 0 and 100
+	Role            string     `json:"role" gorm:"default:user"`                                                                        // required
+	Version         uint       `json:"version" gorm:"not null;default:1"`                                                               // bumped by every update, see UserRepo.UpdateUser
+	PendingEmail    string     `json:"-" gorm:"not null;default:''"`                                                                    // new email waiting for confirmation, Email stays in use until then
+	EmailVerifiedAt *time.Time `json:"-"`                                                                                               // nil until the user opened the link sent to Email
+	AnonymizedAt    *time.Time `json:"-"`                                                                                               // set when the purge scrubbed a deleted user, who can't be restored anymore
+	AuthSource      string     `json:"-" gorm:"not null;default:''"`                                                                    // identity source that provisioned the user (ldap, saml), '' for local accounts
 }
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIssuer is a minimal OpenID Connect issuer used to test the OIDC provider without network access.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string // nonce put into the next id_token
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockIssuer{key: key}
+	mux := http.NewServeMux()
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.server.URL,
+			"aud":            "test-client",
+			"sub":            "upstream-123",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          m.nonce,
+			"email":          "jane@example.com",
+			"email_verified": true,
+			"name":           "Jane Doe",
+		})
+		idToken.Header["kid"] = "test-key"
+		signed, err := idToken.SignedString(key)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "upstream-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     signed,
+		})
+	})
+	return m
+}
+
+func (m *mockIssuer) provider(t *testing.T) Provider {
+	p, err := NewProvider(context.Background(), config.FederationProviderConfig{
+		Name:         "mock",
+		Type:         "oidc",
+		IssuerURL:    m.server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost:8080/api/v1/auth/mock/callback",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+// TestOIDCAuthCodeURL checks that state, nonce and the PKCE challenge are sent to the issuer
+func TestOIDCAuthCodeURL(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider(t)
+
+	u, err := url.Parse(p.AuthCodeURL("the-state", "the-nonce", "the-verifier"))
+	require.NoError(t, err)
+
+	assert.Equal(t, m.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "the-state", u.Query().Get("state"))
+	assert.Equal(t, "the-nonce", u.Query().Get("nonce"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, u.Query().Get("code_challenge"))
+}
+
+// TestOIDCExchange runs the code exchange against the mock issuer
+func TestOIDCExchange(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider(t)
+	m.nonce = "the-nonce"
+
+	identity, err := p.Exchange(context.Background(), "good-code", "the-nonce", "the-verifier")
+	require.NoError(t, err)
+
+	assert.Equal(t, "mock", identity.Provider)
+	assert.Equal(t, "upstream-123", identity.Subject)
+	assert.Equal(t, "jane@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Jane Doe", identity.Name)
+}
+
+// TestOIDCExchangeNonceMismatch makes sure a replayed id_token is rejected
+func TestOIDCExchangeNonceMismatch(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider(t)
+	m.nonce = "other-nonce"
+
+	_, err := p.Exchange(context.Background(), "good-code", "the-nonce", "the-verifier")
+	assert.Error(t, err)
+}
+
+// TestOIDCExchangeBadCode makes sure issuer errors are returned
+func TestOIDCExchangeBadCode(t *testing.T) {
+	m := newMockIssuer(t)
+	p := m.provider(t)
+
+	_, err := p.Exchange(context.Background(), "bad-code", "the-nonce", "the-verifier")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+// Package providers contains the upstream identity providers used for federated ("social") login.
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devesh121/userAuth/pkg/config"
+)
+
+// Identity is the user information returned by an upstream identity provider after a successful login
+type Identity struct {
+	Provider      string // provider name as configured, e.g. "google"
+	Subject       string // stable user ID at the provider
+	Email         string
+	EmailVerified bool // only verified emails are used to link or create accounts
+	Name          string
+}
+
+// Provider is implemented by every upstream identity provider (generic OIDC, plain OAuth2, ...)
+type Provider interface {
+	Name() string
+	// AuthCodeURL builds the URL the browser is redirected to
+	AuthCodeURL(state, nonce, codeVerifier string) string
+	// Exchange trades the authorization code for the user's identity
+	Exchange(ctx context.Context, code, nonce, codeVerifier string) (*Identity, error)
+}
+
+// NewProvider builds a provider from its configuration
+func NewProvider(ctx context.Context, cfg config.FederationProviderConfig) (Provider, error) {
+	switch cfg.Type {
+	case "oidc":
+		return NewOIDCProvider(ctx, cfg)
+	case "oauth2":
+		return NewOAuth2Provider(cfg)
+	default:
+		return nil, fmt.Errorf("unknown provider type %q for provider %q", cfg.Type, cfg.Name)
+	}
+}
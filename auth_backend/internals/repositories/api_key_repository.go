@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepo declares the storage methods for personal API keys
+type APIKeyRepo interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error)      // Method to store a new key
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)          // Method to find a key by its hash
+	GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKey, error)                // Method to find a key by ID
+	ListAPIKeysByUser(ctx context.Context, userID uint) ([]models.APIKey, error)       // Method to list the keys of a user
+	RevokeAPIKey(ctx context.Context, id uint, at time.Time) error                     // Method to revoke a key
+	RevokeAPIKeysByUser(ctx context.Context, userID uint, at time.Time) (int64, error) // Method to revoke all keys of a user
+	RecordAPIKeyUsage(ctx context.Context, id uint, at time.Time, ip string) error     // Method to store last used time and IP
+}
+
+// postgresAPIKeyRepository is the GORM backed implementation of APIKeyRepo
+type postgresAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresAPIKeyRepo returns a new APIKeyRepo backed by PostgreSQL
+func NewPostgresAPIKeyRepo(db *gorm.DB) APIKeyRepo {
+	return &postgresAPIKeyRepository{db: db}
+}
+
+// CreateAPIKey adds a new key
+func (r *postgresAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAPIKeyByHash finds a key by the hash of the presented secret
+func (r *postgresAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByID finds a key by ID
+func (r *postgresAPIKeyRepository) GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeysByUser returns all keys of a user, newest first
+func (r *postgresAPIKeyRepository) ListAPIKeysByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks a key as revoked; the row is kept for the record
+func (r *postgresAPIKeyRepository) RevokeAPIKey(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at).Error
+}
+
+// RevokeAPIKeysByUser revokes every live key of a user and returns how many there were
+func (r *postgresAPIKeyRepository) RevokeAPIKeysByUser(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// RecordAPIKeyUsage stores when and from where a key was last used
+func (r *postgresAPIKeyRepository) RecordAPIKeyUsage(ctx context.Context, id uint, at time.Time, ip string) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": at,
+		"last_used_ip": ip,
+	}).Error
+}
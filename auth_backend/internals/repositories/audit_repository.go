@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows down ListEvents, zero values match everything
+type AuditFilter struct {
+	EventType  string
+	ActorID    uint
+	TargetType string
+	TargetID   string
+	IP         string
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+	Limit      int
+	Offset     int
+}
+
+// auditChainLockKey is the advisory lock serialising writers of the audit chain
+const auditChainLockKey = 727001
+
+// AuditRepo declares the storage methods for the audit log. It is append-only on purpose.
+type AuditRepo interface {
+	AppendEvent(ctx context.Context, event *models.AuditEvent) error                           // Method to add an event, chained to the last one
+	ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, int64, error)    // Method to query events, newest first, with the total count
+	ListEventsAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEvent, error) // Method to walk the chain in order
+	LastEvent(ctx context.Context) (*models.AuditEvent, error)                                 // Method to get the head of the chain
+	AppendCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error            // Method to add a signed checkpoint
+	LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error)                       // Method to get the newest checkpoint
+	ListCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error)                     // Method to list checkpoints, oldest first
+}
+
+// postgresAuditRepository is the GORM backed implementation of AuditRepo
+type postgresAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresAuditRepo returns a new AuditRepo backed by PostgreSQL
+func NewPostgresAuditRepo(db *gorm.DB) AuditRepo {
+	return &postgresAuditRepository{db: db}
+}
+
+// AppendEvent links the event to the current head of the chain and inserts it
+func (r *postgresAuditRepository) AppendEvent(ctx context.Context, event *models.AuditEvent) error {
+	// One writer at a time on every database, so two events never chain onto the same predecessor
+	return serialize(ctx, r.db, auditChainLockKey, func(tx *gorm.DB) error {
+		var head []models.AuditEvent
+		if err := tx.Select("hash").Order("id DESC").Limit(1).Find(&head).Error; err != nil {
+			return err
+		}
+		event.PrevHash = ""
+		if len(head) > 0 {
+			event.PrevHash = head[0].Hash
+		}
+		event.Hash = event.ChainHash()
+		return tx.Create(event).Error
+	})
+}
+
+// ListEvents returns one page of events matching the filter
+func (r *postgresAuditRepository) ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// ListEventsAfter returns up to limit events with an ID above afterID, in chain order
+func (r *postgresAuditRepository) ListEventsAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastEvent returns the newest event
+func (r *postgresAuditRepository) LastEvent(ctx context.Context) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	if err := r.db.WithContext(ctx).Order("id DESC").First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// AppendCheckpoint inserts a checkpoint
+func (r *postgresAuditRepository) AppendCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	return r.db.WithContext(ctx).Create(checkpoint).Error
+}
+
+// LastCheckpoint returns the newest checkpoint
+func (r *postgresAuditRepository) LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error) {
+	var checkpoint models.AuditCheckpoint
+	if err := r.db.WithContext(ctx).Order("id DESC").First(&checkpoint).Error; err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// ListCheckpoints returns all checkpoints, oldest first
+func (r *postgresAuditRepository) ListCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error) {
+	var checkpoints []models.AuditCheckpoint
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
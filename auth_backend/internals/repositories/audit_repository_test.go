@@ -0,0 +1,57 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/pkg/migrate"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestAppendEventConcurrently checks that appends racing each other on SQLite are all stored and
+// chained one after the other, even when the pool has several connections
+func TestAppendEventConcurrently(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "audit.sqlite") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(8)
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrate.New(sqlDB, db.Dialector.Name())
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	repo := repositories.NewPostgresAuditRepo(db)
+
+	const appends = 20
+	var wg sync.WaitGroup
+	for range appends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.AppendEvent(ctx, &models.AuditEvent{EventType: "user.login", CreatedAt: time.Now()}))
+		}()
+	}
+	wg.Wait()
+
+	events, err := repo.ListEventsAfter(ctx, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, events, appends)
+	prev := ""
+	for _, event := range events {
+		assert.Equal(t, prev, event.PrevHash)
+		assert.Equal(t, event.ChainHash(), event.Hash)
+		prev = event.Hash
+	}
+}
@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/cache"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+)
+
+// cachedUserRepository is a UserRepo keeping the users found by GetUserByID and GetUserByEmail
+// in a cache.Store. Every method changing a user drops it from the cache. Replicas with their own
+// memory store can't be told, and a lookup racing with a change may cache the old user, so a
+// change can be seen up to ttl late. The password hash is never cached: the users it returns have
+// none, and UpdateUser keeps the stored one for them. Logins read through Uncached.
+type cachedUserRepository struct {
+	UserRepo // the store being cached, serves every other method
+	store    cache.Store
+	ttl      time.Duration
+}
+
+// NewCachedUserRepo returns next with its user lookups cached in store for ttl. Cache failures
+// are logged and fall back to next.
+func NewCachedUserRepo(next UserRepo, store cache.Store, ttl time.Duration) UserRepo {
+	return &cachedUserRepository{UserRepo: next, store: store, ttl: ttl}
+}
+
+// userIDKey is the cache key of a user, holding the gob encoded models.User
+func userIDKey(id uint) string {
+	return "user:id:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// userEmailKey is the cache key of an email, holding the ID of its user. Entries aren't dropped
+// when the email changes: a lookup only trusts them if the cached user still has that email.
+func userEmailKey(email string) string {
+	return "user:email:" + email
+}
+
+// Uncached returns the store behind repo when it is a cached UserRepo, repo otherwise
+func Uncached(repo UserRepo) UserRepo {
+	if cached, ok := repo.(*cachedUserRepository); ok {
+		return cached.UserRepo
+	}
+	return repo
+}
+
+// GetUserByID returns the cached user, or loads and caches it, without its password hash
+func (r *cachedUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	user, err := r.cached(ctx, id)
+	switch {
+	case err != nil:
+		r.failed("id", err)
+	case user != nil:
+		r.count("id", "hit")
+		return user, nil
+	default:
+		r.count("id", "miss")
+	}
+
+	if user, err = r.UserRepo.GetUserByID(ctx, id); err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	r.remember(ctx, user)
+	return user, nil
+}
+
+// GetUserByEmail returns the cached user of the email, or loads and caches it, without its
+// password hash
+func (r *cachedUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.cachedByEmail(ctx, email)
+	switch {
+	case err != nil:
+		r.failed("email", err)
+	case user != nil:
+		r.count("email", "hit")
+		return user, nil
+	default:
+		r.count("email", "miss")
+	}
+
+	if user, err = r.UserRepo.GetUserByEmail(ctx, email); err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	r.remember(ctx, user)
+	return user, nil
+}
+
+// UpdateUser saves the user and drops it from the cache, also when the version was stale. A user
+// without a password hash, as returned by the lookups, keeps the stored one; its version was
+// read along with it, so a hash changed meanwhile fails the update as stale.
+func (r *cachedUserRepository) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	defer r.forget(ctx, user.ID)
+	if user.Password == "" {
+		stored, err := r.UserRepo.GetUserByID(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = stored.Password
+	}
+	return r.UserRepo.UpdateUser(ctx, user)
+}
+
+// DeleteUser deletes the user and drops it from the cache
+func (r *cachedUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	defer r.forget(ctx, id)
+	return r.UserRepo.DeleteUser(ctx, id)
+}
+
+// RestoreUser restores the user and drops it from the cache
+func (r *cachedUserRepository) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepo.RestoreUser(ctx, id)
+}
+
+// PurgeDeletedUsers purges the users and drops them from the cache
+func (r *cachedUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, mode PurgeMode, limit int) ([]models.User, error) {
+	purged, err := r.UserRepo.PurgeDeletedUsers(ctx, before, mode, limit)
+	ids := make([]uint, len(purged))
+	for i, user := range purged {
+		ids[i] = user.ID
+	}
+	r.forget(ctx, ids...)
+	return purged, err
+}
+
+// cached returns the cached user of the ID, nil when it isn't cached
+func (r *cachedUserRepository) cached(ctx context.Context, id uint) (*models.User, error) {
+	value, found, err := r.store.Get(ctx, userIDKey(id))
+	if err != nil || !found {
+		return nil, err
+	}
+	var user models.User
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&user); err != nil {
+		return nil, fmt.Errorf("decode user %d: %w", id, err)
+	}
+	return &user, nil
+}
+
+// cachedByEmail returns the cached user of the email, nil when it isn't cached or has another
+// email by now
+func (r *cachedUserRepository) cachedByEmail(ctx context.Context, email string) (*models.User, error) {
+	value, found, err := r.store.Get(ctx, userEmailKey(email))
+	if err != nil || !found {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user ID of %s: %w", email, err)
+	}
+	user, err := r.cached(ctx, uint(id))
+	if err != nil || user == nil || user.Email != email {
+		return nil, err
+	}
+	return user, nil
+}
+
+// remember caches the user under its ID, and its ID under its email
+func (r *cachedUserRepository) remember(ctx context.Context, user *models.User) {
+	var value bytes.Buffer
+	if err := gob.NewEncoder(&value).Encode(user); err != nil {
+		log.Printf("user cache: encode user %d: %v", user.ID, err)
+		return
+	}
+	if err := r.store.Set(ctx, userIDKey(user.ID), value.Bytes(), r.ttl); err != nil {
+		log.Printf("user cache: %v", err)
+		return
+	}
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	if err := r.store.Set(ctx, userEmailKey(user.Email), []byte(id), r.ttl); err != nil {
+		log.Printf("user cache: %v", err)
+	}
+}
+
+// forget drops users from the cache
+func (r *cachedUserRepository) forget(ctx context.Context, ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userIDKey(id)
+	}
+	// The change is made, so drop the entries even if the request was cancelled meanwhile
+	if err := r.store.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		log.Printf("user cache: failed to drop users %v, they may be stale until they expire: %v", ids, err)
+	}
+}
+
+// count records the result of a lookup: "hit", "miss" or "error"
+func (r *cachedUserRepository) count(lookup, result string) {
+	metrics.UserCacheRequestsTotal.WithLabelValues(lookup, result).Inc()
+}
+
+// failed logs a cache error, the lookup goes to the database
+func (r *cachedUserRepository) failed(lookup string, err error) {
+	r.count(lookup, "error")
+	log.Printf("user cache: %v", err)
+}
@@ -0,0 +1,108 @@
+package repositories_test
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/devesh121/userAuth/internals/cache"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingUserRepo counts the lookups reaching the wrapped store
+type countingUserRepo struct {
+	repositories.UserRepo
+	lookups atomic.Int64
+}
+
+func (r *countingUserRepo) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	r.lookups.Add(1)
+	return r.UserRepo.GetUserByID(ctx, id)
+}
+
+func (r *countingUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.lookups.Add(1)
+	return r.UserRepo.GetUserByEmail(ctx, email)
+}
+
+// TestCachedUserRepo checks that lookups are served from the cache until the user changes, on
+// both backends
+func TestCachedUserRepo(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func(t *testing.T) cache.Store{
+		"lru": func(t *testing.T) cache.Store { return cache.NewLRU(100) },
+		"redis": func(t *testing.T) cache.Store {
+			store, err := cache.NewRedis("redis://" + miniredis.RunT(t).Addr())
+			require.NoError(t, err)
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			backend := &countingUserRepo{UserRepo: repositories.NewMemoryUserRepo()}
+			store := newStore(t)
+			repo := repositories.NewCachedUserRepo(backend, store, time.Minute)
+			hits := testutil.ToFloat64(metrics.UserCacheRequestsTotal.WithLabelValues("email", "hit"))
+			misses := testutil.ToFloat64(metrics.UserCacheRequestsTotal.WithLabelValues("email", "miss"))
+
+			ann, err := repo.CreateUser(ctx, &models.User{Name: "Ann", Email: "ann@example.com", Password: "$2a$10$secret-hash", Age: 30})
+			require.NoError(t, err)
+
+			// The first lookup loads the user, the next ones by ID or email are cached, all but the
+			// password hash
+			for range 3 {
+				user, err := repo.GetUserByEmail(ctx, "ann@example.com")
+				require.NoError(t, err)
+				assert.Equal(t, "Ann", user.Name)
+				assert.Empty(t, user.Password)
+			}
+			cached, found, err := store.Get(ctx, "user:id:"+strconv.FormatUint(uint64(ann.ID), 10))
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.NotContains(t, string(cached), "secret-hash")
+			_, err = repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, backend.lookups.Load())
+			assert.Equal(t, hits+2, testutil.ToFloat64(metrics.UserCacheRequestsTotal.WithLabelValues("email", "hit")))
+			assert.Equal(t, misses+1, testutil.ToFloat64(metrics.UserCacheRequestsTotal.WithLabelValues("email", "miss")))
+
+			// Callers get their own copy
+			user, err := repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			user.Name = "changed in place"
+			user, err = repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Ann", user.Name)
+
+			// An update drops the user, the old email doesn't find it anymore
+			user.Email = "ann.b@example.com"
+			_, err = repo.UpdateUser(ctx, user)
+			require.NoError(t, err)
+			user, err = repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "ann.b@example.com", user.Email)
+			_, err = repo.GetUserByEmail(ctx, "ann@example.com")
+			assert.Error(t, err)
+
+			// The update kept the hash, which logins read from the store behind the cache
+			user, err = repositories.Uncached(repo).GetUserByEmail(ctx, "ann.b@example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "$2a$10$secret-hash", user.Password)
+
+			// So does a delete
+			require.NoError(t, repo.DeleteUser(ctx, ann.ID))
+			_, err = repo.GetUserByID(ctx, ann.ID)
+			assert.Error(t, err)
+			_, err = repo.GetUserByEmail(ctx, "ann.b@example.com")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsTimeout reports whether a query ran out of time: the request's deadline passed, or
+// PostgreSQL cancelled the statement (query_canceled, e.g. statement_timeout)
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+// IsUnavailable reports whether the database can't serve queries right now: it can't be reached,
+// the connection broke, it is shutting down or out of connections
+func IsUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 connection exception, 53300 too_many_connections, 57P01-57P03 shutdown / cannot connect now
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "53300" || strings.HasPrefix(pgErr.Code, "57P0")
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
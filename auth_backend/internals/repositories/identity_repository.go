@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// IdentityRepo stores the links between upstream provider accounts and local users
+type IdentityRepo interface {
+	CreateIdentity(ctx context.Context, identity *models.LinkedIdentity) (*models.LinkedIdentity, error) // Method to link a provider account
+	GetIdentity(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error)           // Method to find a link by provider + subject
+}
+
+// postgresIdentityRepository is the GORM backed implementation of IdentityRepo
+type postgresIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresIdentityRepo returns a new IdentityRepo backed by PostgreSQL
+func NewPostgresIdentityRepo(db *gorm.DB) IdentityRepo {
+	return &postgresIdentityRepository{db: db}
+}
+
+// CreateIdentity adds a new linked identity
+func (r *postgresIdentityRepository) CreateIdentity(ctx context.Context, identity *models.LinkedIdentity) (*models.LinkedIdentity, error) {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// GetIdentity finds the linked identity for a provider account
+func (r *postgresIdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error) {
+	var identity models.LinkedIdentity
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
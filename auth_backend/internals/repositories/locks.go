@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// localLocks stand in for advisory locks on databases without them. A SQLite database belongs to
+// one process, so a lock of that process is enough; keys share the stripes modulo their count.
+var localLocks [64]sync.Mutex
+
+// serialize runs fn in a transaction holding the lock key until it ends, so that callers using
+// the same key run one at a time: a transaction level advisory lock on PostgreSQL, a process
+// lock elsewhere.
+func serialize(ctx context.Context, db *gorm.DB, key int64, fn func(tx *gorm.DB) error) error {
+	if db.Dialector.Name() == "postgres" {
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", key).Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	}
+
+	mu := &localLocks[uint64(key)%uint64(len(localLocks))]
+	mu.Lock()
+	defer mu.Unlock()
+	return db.WithContext(ctx).Transaction(fn)
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// LoginHistoryRepo declares the storage methods for login attempts
+type LoginHistoryRepo interface {
+	CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error                                  // Method to store an attempt
+	ListLoginAttempts(ctx context.Context, userID uint, limit, offset int) ([]models.LoginAttempt, int64, error) // Method to list a user's attempts, newest first
+	HasSuccessfulLogin(ctx context.Context, userID uint) (bool, error)                                           // Method to check whether the user ever logged in
+	KnownFingerprint(ctx context.Context, userID uint, fingerprint string) (bool, error)                         // Method to check a device was used before
+	KnownNetwork(ctx context.Context, userID uint, network string) (bool, error)                                 // Method to check a network was used before
+}
+
+// postgresLoginHistoryRepository is the GORM backed implementation of LoginHistoryRepo
+type postgresLoginHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresLoginHistoryRepo returns a new LoginHistoryRepo backed by PostgreSQL
+func NewPostgresLoginHistoryRepo(db *gorm.DB) LoginHistoryRepo {
+	return &postgresLoginHistoryRepository{db: db}
+}
+
+// CreateLoginAttempt adds an attempt
+func (r *postgresLoginHistoryRepository) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// ListLoginAttempts returns one page of the user's attempts with the total count
+func (r *postgresLoginHistoryRepository) ListLoginAttempts(ctx context.Context, userID uint, limit, offset int) ([]models.LoginAttempt, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.LoginAttempt{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// HasSuccessfulLogin reports whether the user has any successful login
+func (r *postgresLoginHistoryRepository) HasSuccessfulLogin(ctx context.Context, userID uint) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success", userID)
+}
+
+// KnownFingerprint reports whether the user logged in successfully with this device before
+func (r *postgresLoginHistoryRepository) KnownFingerprint(ctx context.Context, userID uint, fingerprint string) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success AND fingerprint = ?", userID, fingerprint)
+}
+
+// KnownNetwork reports whether the user logged in successfully from this network before
+func (r *postgresLoginHistoryRepository) KnownNetwork(ctx context.Context, userID uint, network string) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success AND network = ?", userID, network)
+}
+
+// exists reports whether any attempt matches the condition
+func (r *postgresLoginHistoryRepository) exists(ctx context.Context, condition string, args ...any) (bool, error) {
+	var found []models.LoginAttempt
+	err := r.db.WithContext(ctx).Select("id").Where(condition, args...).Limit(1).Find(&found).Error
+	return len(found) > 0, err
+}
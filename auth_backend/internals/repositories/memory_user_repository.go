@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// memoryUserRepository keeps users in a map, for local development and tests. It behaves like the
+// SQL repositories: emails are unique (case-sensitive) among users that aren't deleted, deletes
+// are soft and can be undone until purged, a missing user is gorm.ErrRecordNotFound, and a cancelled or expired
+// context fails the call with the context's error.
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User // stored by value, callers only ever get copies
+	db     *gorm.DB             // holds the credentials and login history of the users, if set
+}
+
+// NewMemoryUserRepo returns an empty, thread-safe in-memory UserRepo
+func NewMemoryUserRepo() UserRepo {
+	return &memoryUserRepository{users: make(map[uint]models.User)}
+}
+
+// NewMemoryUserRepoOn returns an empty in-memory UserRepo whose users keep their API keys,
+// linked identities, sessions and login attempts in db, so purges delete those too
+func NewMemoryUserRepoOn(db *gorm.DB) UserRepo {
+	return &memoryUserRepository{users: make(map[uint]models.User), db: db}
+}
+
+// CreateUser adds a new user, filling in ID, timestamps and the default role like the database
+func (r *memoryUserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return nil, ErrDuplicateEmail
+	}
+
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.Version = 1
+	user.CreatedAt, user.UpdatedAt = now, now
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	r.users[user.ID] = *user
+	return user, nil
+}
+
+// GetUserByEmail finds a user by email
+func (r *memoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetUserByID finds a user by ID
+func (r *memoryUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// GetAllUsers returns the users that aren't deleted, ordered by ID
+func (r *memoryUserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]models.User, 0, len(r.users))
+	for id := uint(1); id <= r.nextID; id++ {
+		if user, ok := r.users[id]; ok && !user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// ListUsers filters, sorts and pages the users that aren't deleted, like the SQL repositories
+func (r *memoryUserRepository) ListUsers(ctx context.Context, query UserListQuery) ([]models.User, int64, error) {
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	if !slices.Contains(UserSortFields, sortBy) {
+		return nil, 0, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+
+	users, err := r.GetAllUsers(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	users = slices.DeleteFunc(users, func(user models.User) bool { return !query.Filter.matches(user) })
+	total := int64(len(users))
+
+	// Sort order: the sort column, then ID
+	compare := func(a, b UserKey) int {
+		c := compareValues(a.Value, b.Value)
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if query.Desc {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		return compare(userKey(a, sortBy), userKey(b, sortBy))
+	})
+	if query.After != nil {
+		after := *query.After
+		if sortBy == "id" {
+			after.Value = after.ID
+		}
+		users = slices.DeleteFunc(users, func(user models.User) bool { return compare(userKey(user, sortBy), after) <= 0 })
+	}
+
+	users = users[min(query.Offset, len(users)):]
+	if query.Limit > 0 && len(users) > query.Limit {
+		users = users[:query.Limit]
+	}
+	return users, total, nil
+}
+
+// matches applies the filter to one user
+func (f UserFilter) matches(user models.User) bool {
+	contains := func(value, part string) bool {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(part))
+	}
+	switch {
+	case f.Role != "" && user.Role != f.Role,
+		f.MinAge != nil && user.Age < *f.MinAge,
+		f.MaxAge != nil && user.Age > *f.MaxAge,
+		!f.CreatedFrom.IsZero() && user.CreatedAt.Before(f.CreatedFrom),
+		!f.CreatedTo.IsZero() && !user.CreatedAt.Before(f.CreatedTo),
+		f.Email != "" && !contains(user.Email, f.Email),
+		f.Name != "" && !contains(user.Name, f.Name):
+		return false
+	}
+	return true
+}
+
+// userKey returns the position of user in the order of sortBy
+func userKey(user models.User, sortBy string) UserKey {
+	key := UserKey{ID: user.ID}
+	switch sortBy {
+	case "name":
+		key.Value = user.Name
+	case "email":
+		key.Value = user.Email
+	case "age":
+		key.Value = user.Age
+	case "created_at":
+		key.Value = user.CreatedAt
+	default:
+		key.Value = user.ID
+	}
+	return key
+}
+
+// compareValues compares two sort values of the same column
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return cmp.Compare(a, b.(string))
+	case int:
+		return cmp.Compare(a, b.(int))
+	case uint:
+		return cmp.Compare(a, b.(uint))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// UpdateUser replaces a stored user if it still has user.Version, and bumps the version
+func (r *memoryUserRepository) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if stored.Version != user.Version {
+		return nil, ErrStaleUser
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return nil, ErrDuplicateEmail
+	}
+
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[user.ID] = *user
+	return user, nil
+}
+
+// DeleteUser soft deletes a user by ID, deleting an unknown ID is not an error
+func (r *memoryUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok && !user.DeletedAt.Valid {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		r.users[id] = user
+	}
+	return nil
+}
+
+// emailTaken reports whether another user that isn't deleted has the email
+func (r *memoryUserRepository) emailTaken(email string, exceptID uint) bool {
+	for id, user := range r.users {
+		if id != exceptID && user.Email == email && !user.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
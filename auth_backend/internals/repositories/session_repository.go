@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// SessionRepo declares the storage methods for login sessions
+type SessionRepo interface {
+	CreateSession(ctx context.Context, session *models.Session) (*models.Session, error)                // Method to store a new session
+	GetSession(ctx context.Context, id string) (*models.Session, error)                                 // Method to find a session by ID
+	ListActiveSessionsByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) // Method to list unexpired, not revoked sessions
+	RevokeSession(ctx context.Context, id string, at time.Time) error                                   // Method to terminate one session
+	RevokeOtherSessions(ctx context.Context, userID uint, keepID string, at time.Time) (int64, error)   // Method to terminate all sessions but one
+	TouchSession(ctx context.Context, id string, at time.Time) error                                    // Method to update the last seen time
+	CountActiveUsers(ctx context.Context, now time.Time) (int64, error)                                 // Method to count users with a live session
+	LockUserSessions(ctx context.Context, userID uint, fn func(repo SessionRepo) error) error           // Method to change the sessions of a user one caller at a time
+}
+
+// sessionLockSpace is the high half of the advisory lock keys of users' sessions, the low half
+// is the user ID
+const sessionLockSpace = 727002 << 32
+
+// postgresSessionRepository is the GORM backed implementation of SessionRepo
+type postgresSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresSessionRepo returns a new SessionRepo backed by PostgreSQL
+func NewPostgresSessionRepo(db *gorm.DB) SessionRepo {
+	return &postgresSessionRepository{db: db}
+}
+
+// CreateSession adds a new session
+func (r *postgresSessionRepository) CreateSession(ctx context.Context, session *models.Session) (*models.Session, error) {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSession finds a session by ID
+func (r *postgresSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveSessionsByUser returns the user's live sessions, most recently used first
+func (r *postgresSessionRepository) ListActiveSessionsByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession terminates a session
+func (r *postgresSessionRepository) RevokeSession(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at).Error
+}
+
+// RevokeOtherSessions terminates every live session of the user except keepID
+func (r *postgresSessionRepository) RevokeOtherSessions(ctx context.Context, userID uint, keepID string, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// TouchSession updates the last seen time of a session
+func (r *postgresSessionRepository) TouchSession(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+// LockUserSessions runs fn in a transaction holding the lock of the user's sessions, with a repo
+// bound to that transaction, so that checking the sessions and then adding one can't race with
+// another login of the same user
+func (r *postgresSessionRepository) LockUserSessions(ctx context.Context, userID uint, fn func(repo SessionRepo) error) error {
+	return serialize(ctx, r.db, sessionLockSpace|int64(userID), func(tx *gorm.DB) error {
+		return fn(&postgresSessionRepository{db: tx})
+	})
+}
+
+// CountActiveUsers returns the number of users with at least one unexpired, not revoked session
+func (r *postgresSessionRepository) CountActiveUsers(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("revoked_at IS NULL AND expires_at > ?", now).
+		Distinct("user_id").
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,70 @@
+package repositories_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCountActiveUsers checks that only unexpired, not revoked sessions count, once per user
+func TestCountActiveUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewPostgresSessionRepo(newSQLiteDB(t))
+	now := time.Now()
+
+	for _, session := range []models.Session{
+		{ID: "a1", UserID: 1, ExpiresAt: now.Add(time.Hour)},
+		{ID: "a2", UserID: 1, ExpiresAt: now.Add(time.Hour)},
+		{ID: "b1", UserID: 2, ExpiresAt: now.Add(time.Hour)},
+		{ID: "c1", UserID: 3, ExpiresAt: now.Add(-time.Minute)},
+	} {
+		_, err := repo.CreateSession(ctx, &session)
+		require.NoError(t, err)
+	}
+	count, err := repo.CountActiveUsers(ctx, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	require.NoError(t, repo.RevokeSession(ctx, "b1", now))
+	count, err = repo.CountActiveUsers(ctx, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
+
+// TestLockUserSessions checks that callers holding the lock of a user's sessions take turns, so
+// that a check of the sessions followed by an insert can't race
+func TestLockUserSessions(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewPostgresSessionRepo(newSQLiteDB(t))
+	const limit = 3
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.LockUserSessions(ctx, 1, func(locked repositories.SessionRepo) error {
+				active, err := locked.ListActiveSessionsByUser(ctx, 1, time.Now())
+				if err != nil || len(active) >= limit {
+					return err
+				}
+				time.Sleep(time.Millisecond) // give a racing caller the chance to see the same count
+				_, err = locked.CreateSession(ctx, &models.Session{ID: "s" + strconv.Itoa(i), UserID: 1, ExpiresAt: time.Now().Add(time.Hour)})
+				return err
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	active, err := repo.ListActiveSessionsByUser(ctx, 1, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, active, limit)
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+)
+
+// sqliteUserRepository is the UserRepo on SQLite, through the pure Go driver (no cgo), so local
+// development and tests run without PostgreSQL. The GORM queries are the same as on PostgreSQL,
+// and the users table comes from the sqlite migrations with the same partial unique email index.
+type sqliteUserRepository struct {
+	postgresUserRepository
+}
+
+// NewSQLiteUserRepo returns a UserRepo on a SQLite database opened with github.com/glebarez/sqlite
+func NewSQLiteUserRepo(db *gorm.DB) UserRepo {
+	return &sqliteUserRepository{postgresUserRepository{db: db}}
+}
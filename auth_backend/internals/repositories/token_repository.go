@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRepo is the revocation store for issued JWTs
+type TokenRepo interface {
+	RevokeToken(ctx context.Context, token *models.RevokedToken) error // Method to mark a token as revoked
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)      // Method to check if a token was revoked
+}
+
+// postgresTokenRepository is the GORM backed implementation of TokenRepo
+type postgresTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresTokenRepo returns a new TokenRepo backed by PostgreSQL
+func NewPostgresTokenRepo(db *gorm.DB) TokenRepo {
+	return &postgresTokenRepository{db: db}
+}
+
+// RevokeToken stores the token ID; revoking an already revoked token is a no-op
+func (r *postgresTokenRepository) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsTokenRevoked reports whether the given token ID is in the revocation store
+func (r *postgresTokenRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// PurgeMode is what PurgeDeletedUsers does with users deleted before the retention cut-off
+type PurgeMode string
+
+const (
+	PurgeDelete    PurgeMode = "delete"    // remove the rows for good
+	PurgeAnonymize PurgeMode = "anonymize" // keep the rows, so their IDs still resolve, but scrub name, email, password and age
+)
+
+// ListDeletedUsers returns one page of the soft-deleted users that can still be restored, most
+// recently deleted first, and how many there are in total
+func (r *postgresUserRepository) ListDeletedUsers(ctx context.Context, limit, offset int) ([]models.User, int64, error) {
+	db := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL AND anonymized_at IS NULL")
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := db.Session(&gorm.Session{}).Order("deleted_at DESC").Order("id DESC")
+	if limit > 0 {
+		page = page.Limit(limit)
+	}
+	var users []models.User
+	if err := page.Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// RestoreUser undeletes a soft-deleted user. gorm.ErrRecordNotFound when there is no such deleted
+// user (or it was anonymized), ErrDuplicateEmail when a live user has taken the email meanwhile.
+func (r *postgresUserRepository) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	db := r.db.WithContext(ctx)
+
+	var user models.User
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL AND anonymized_at IS NULL").First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	result := db.Unscoped().Model(&user).Where("deleted_at IS NOT NULL").
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, translateUserError(r.db, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound // restored or purged concurrently
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+	return &user, nil
+}
+
+// PurgeDeletedUsers deletes or anonymizes up to limit users (0: all) soft-deleted before the
+// cut-off, oldest first, and returns them as they were. Their linked identities, API keys and
+// sessions are removed in the same transaction, in both modes.
+func (r *postgresUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, mode PurgeMode, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Step 1: Pick the users past retention
+		query := tx.Unscoped().Where("deleted_at < ? AND anonymized_at IS NULL", before).Order("deleted_at").Order("id")
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		if err := query.Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+
+		// Step 2: Their credentials and login history go in both modes
+		if err := deleteUserData(tx, ids); err != nil {
+			return err
+		}
+
+		// Step 3: Then the users themselves
+		if mode == PurgeDelete {
+			return tx.Unscoped().Delete(&models.User{}, ids).Error
+		}
+		return tx.Unscoped().Model(&models.User{}).Where("id IN ?", ids).Updates(map[string]any{
+			"name": "", "email": "", "pending_email": "", "password": "", "age": 0, "email_verified_at": nil, "anonymized_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListDeletedUsers pages the soft-deleted users that aren't anonymized, most recently deleted first
+func (r *memoryUserRepository) ListDeletedUsers(ctx context.Context, limit, offset int) ([]models.User, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []models.User
+	for id := r.nextID; id > 0; id-- {
+		if user, ok := r.users[id]; ok && restorable(user) {
+			users = append(users, user)
+		}
+	}
+	slices.SortStableFunc(users, func(a, b models.User) int { return b.DeletedAt.Time.Compare(a.DeletedAt.Time) })
+	total := int64(len(users))
+
+	users = users[min(offset, len(users)):]
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+// RestoreUser undeletes a soft-deleted user, unless a live user has its email now
+func (r *memoryUserRepository) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !restorable(user) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if r.emailTaken(user.Email, id) {
+		return nil, ErrDuplicateEmail
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return &user, nil
+}
+
+// PurgeDeletedUsers deletes or anonymizes the users soft-deleted before the cut-off, oldest first
+func (r *memoryUserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, mode PurgeMode, limit int) ([]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []models.User
+	for _, user := range r.users {
+		if restorable(user) && user.DeletedAt.Time.Before(before) {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		if c := a.DeletedAt.Time.Compare(b.DeletedAt.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+
+	// Their credentials and login history go first, in both modes
+	if r.db != nil && len(users) > 0 {
+		ids := make([]uint, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return deleteUserData(tx, ids) }); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		if mode == PurgeDelete {
+			delete(r.users, user.ID)
+			continue
+		}
+		scrubbed := user
+		scrubbed.Name, scrubbed.Email, scrubbed.PendingEmail, scrubbed.Password, scrubbed.Age = "", "", "", "", 0
+		scrubbed.EmailVerifiedAt, scrubbed.AnonymizedAt = nil, &now
+		r.users[user.ID] = scrubbed
+	}
+	return users, nil
+}
+
+// deleteUserData deletes the linked identities, API keys, sessions and login attempts of the users
+func deleteUserData(tx *gorm.DB, ids []uint) error {
+	for _, model := range []any{&models.LinkedIdentity{}, &models.APIKey{}, &models.Session{}, &models.LoginAttempt{}} {
+		if err := tx.Unscoped().Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorable reports whether a stored user is soft deleted and still has its data
+func restorable(user models.User) bool {
+	return user.DeletedAt.Valid && user.AnonymizedAt == nil
+}
@@ -1,18 +1,67 @@
 package repositories
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/devesh121/userAuth/internals/models" // Importing User model
 	"gorm.io/gorm"                                   // GORM ORM package for DB access
 )
 
+// Errors shared by every UserRepo implementation, so services don't depend on a driver.
+// A missing user is reported as gorm.ErrRecordNotFound by all of them.
+var (
+	ErrDuplicateEmail = errors.New("user already exists")                 // email taken by a user that isn't deleted
+	ErrStaleUser      = errors.New("user was modified since it was read") // UpdateUser with an outdated Version
+)
+
+// UserFilter narrows down ListUsers, zero values match everything
+type UserFilter struct {
+	Role        string
+	MinAge      *int
+	MaxAge      *int
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+	Email       string    // case-insensitive substring
+	Name        string    // case-insensitive substring
+}
+
+// UserSortFields are the columns ListUsers can order by
+var UserSortFields = []string{"id", "name", "email", "age", "created_at"}
+
+// UserKey is the position of a row in a sort order: the value of the sort column and the ID
+type UserKey struct {
+	Value any // string for name and email, int for age, time.Time for created_at, uint for id
+	ID    uint
+}
+
+// UserListQuery selects one page of users. Ties on SortBy are broken by ID, so pages are stable.
+type UserListQuery struct {
+	Filter UserFilter
+	SortBy string // one of UserSortFields, default "id"
+	Desc   bool
+	Limit  int // 0: no limit
+	Offset int
+	After  *UserKey // keyset paging: only rows after this one in the sort order
+}
+
 // UserRepo interface declares methods to be implemented :- matlab inhe implement karna hai but kaise vo nahi batata hai.
 type UserRepo interface {
-	CreateUser(user *models.User) (*models.User, error) // Method to create new user
-	GetUserByEmail(email string) (*models.User, error)  // Method to find user by email
-	GetUserByID(id uint) (*models.User, error)          // Method to find user by ID
-	GetAllUsers() ([]models.User, error)                // Method to get all users
-	UpdateUser(user *models.User) (*models.User, error) // Method to update user
-	DeleteUser(id uint) error                           // Method to delete user
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)                                   // Method to create new user
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)                                    // Method to find user by email
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)                                            // Method to find user by ID
+	GetAllUsers(ctx context.Context) ([]models.User, error)                                                    // Method to get all users
+	ListUsers(ctx context.Context, query UserListQuery) ([]models.User, int64, error)                          // Method to get one page of users with the total matching the filter
+	SearchUsers(ctx context.Context, query string, limit int) ([]UserMatch, error)                             // Method to find users by words of their name or email, best match first
+	UpdateUser(ctx context.Context, user *models.User) (*models.User, error)                                   // Method to update user if its Version is still current, bumping it
+	DeleteUser(ctx context.Context, id uint) error                                                             // Method to soft delete user
+	ListDeletedUsers(ctx context.Context, limit, offset int) ([]models.User, int64, error)                     // Method to get one page of restorable deleted users with their total
+	RestoreUser(ctx context.Context, id uint) (*models.User, error)                                            // Method to undelete user
+	PurgeDeletedUsers(ctx context.Context, before time.Time, mode PurgeMode, limit int) ([]models.User, error) // Method to delete or anonymize users deleted before a time
 }
 
 // postgresUserRepository is the concrete implementation of UserRepo using PostgreSQL (via GORM)
@@ -32,56 +81,159 @@ func NewPostgresUserRepo(db *gorm.DB) UserRepo {
 }
 
 // CreateUser adds a new user to the database
-func (r *postgresUserRepository) CreateUser(user *models.User) (*models.User, error) {
-	if err := r.db.Create(user).Error; err != nil {
-		return nil, err
+func (r *postgresUserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	user.Version = 1
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, translateUserError(r.db, err)
 	}
 	return user, nil
 }
 
 // GetUserByEmail finds a user by email
-func (r *postgresUserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (r *postgresUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByID finds a user by ID
-func (r *postgresUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *postgresUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetAllUsers fetches all users from the DB
-func (r *postgresUserRepository) GetAllUsers() ([]models.User, error) {
+func (r *postgresUserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := r.db.Find(&users).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil // users ek slice hai isliye uska refrence yaha return me pass hoga isliye use "&users" aisa likhne ki jarurat nahi hai.
 }
 
-// UpdateUser modifies an existing user record
-func (r *postgresUserRepository) UpdateUser(user *models.User) (*models.User, error) {
-	if err := r.db.Save(user).Error; err != nil {
-		return nil, err // Return error if update fails
+// ListUsers returns one page of users matching the filter, and how many match in total
+func (r *postgresUserRepository) ListUsers(ctx context.Context, query UserListQuery) ([]models.User, int64, error) {
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	if !slices.Contains(UserSortFields, sortBy) {
+		return nil, 0, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+
+	// Step 1: Filters, shared by the count and the page
+	db := r.db.WithContext(ctx).Model(&models.User{})
+	f := query.Filter
+	if f.Role != "" {
+		db = db.Where("role = ?", f.Role)
+	}
+	if f.MinAge != nil {
+		db = db.Where("age >= ?", *f.MinAge)
+	}
+	if f.MaxAge != nil {
+		db = db.Where("age <= ?", *f.MaxAge)
+	}
+	if !f.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", f.CreatedTo)
+	}
+	if f.Email != "" {
+		db = db.Where(`LOWER(email) LIKE ? ESCAPE '\'`, containsPattern(f.Email))
+	}
+	if f.Name != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, containsPattern(f.Name))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Step 2: Keyset condition, then order and page
+	op, dir := ">", "ASC"
+	if query.Desc {
+		op, dir = "<", "DESC"
+	}
+	page := db.Session(&gorm.Session{})
+	if after := query.After; after != nil {
+		if sortBy == "id" {
+			page = page.Where("id "+op+" ?", after.ID)
+		} else {
+			page = page.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortBy, op), after.Value, after.Value, after.ID)
+		}
+	}
+	if sortBy != "id" {
+		page = page.Order(sortBy + " " + dir)
+	}
+	page = page.Order("id " + dir)
+
+	if query.Limit > 0 {
+		page = page.Limit(query.Limit)
+	}
+	var users []models.User
+	if err := page.Offset(query.Offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// containsPattern returns a LIKE pattern (escape character \) matching values that contain s, ignoring case
+func containsPattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(s))
+	return "%" + escaped + "%"
+}
+
+// UpdateUser modifies an existing user record. The write only applies while the row still has
+// user.Version, which it then increments, so concurrent edits can't overwrite each other:
+// the loser gets ErrStaleUser.
+func (r *postgresUserRepository) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	db := r.db.WithContext(ctx)
+
+	read := user.Version
+	user.Version++
+	result := db.Model(user).Where("version = ?", read).Select("*").Omit("id", "created_at").Updates(user)
+	if result.Error != nil {
+		user.Version = read
+		return nil, translateUserError(r.db, result.Error) // Return error if update fails
+	}
+	if result.RowsAffected == 0 {
+		user.Version = read
+		// Either the user is gone or someone else updated it first
+		if err := db.Select("id").First(&models.User{}, user.ID).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrStaleUser
 	}
 	return user, nil
 }
 
 // DeleteUser removes a user by ID
-func (r *postgresUserRepository) DeleteUser(id uint) error {
-	if err := r.db.Delete(&models.User{}, id).Error; err != nil {
+func (r *postgresUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Delete(&models.User{}, id).Error; err != nil {
 		return err // Return error if delete fails
 	}
 	return nil
 }
 
+// translateUserError maps the unique violation of the driver (PostgreSQL 23505, SQLite
+// SQLITE_CONSTRAINT_UNIQUE) to ErrDuplicateEmail
+func translateUserError(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateEmail
+	}
+	return err
+}
+
 // NOTE for me:
 // If struct is passed as pointer → return as it is
 // If struct is local inside function → return its address (&user)
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"testing"
 
 	"github.com/devesh121/userAuth/internals/models"
@@ -16,7 +17,7 @@ type mockUserRepo struct {
 }
 
 // CreateUser mocks the behavior of the CreateUser method in the repository.
-func (m *mockUserRepo) CreateUser(user *models.User) (*models.User, error) {
+func (m *mockUserRepo) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	args := m.Called(user) // Register the method call and return mocked arguments.
 	if args.Get(0) == nil {
 		return nil, args.Error(1) // Return nil and the mocked error if the first argument is nil.
@@ -25,7 +26,7 @@ func (m *mockUserRepo) CreateUser(user *models.User) (*models.User, error) {
 }
 
 // GetUserByEmail mocks the behavior of the GetUserByEmail method in the repository.
-func (m *mockUserRepo) GetUserByEmail(email string) (*models.User, error) {
+func (m *mockUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email) // Register the method call and return mocked arguments.
 	if args.Get(0) == nil {
 		return nil, args.Error(1) // Return nil and the mocked error if the first argument is nil.
@@ -34,31 +35,32 @@ func (m *mockUserRepo) GetUserByEmail(email string) (*models.User, error) {
 }
 
 // GetAllUsers mocks the behavior of the GetAllUsers method in the repository.
-func (m *mockUserRepo) GetAllUsers() ([]models.User, error) {
+func (m *mockUserRepo) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	args := m.Called()                                // Register the method call and return mocked arguments.
 	return args.Get(0).([]models.User), args.Error(1) // Return the mocked list of users and error.
 }
 
 // GetUserByID mocks the behavior of the GetUserByID method in the repository.
-func (m *mockUserRepo) GetUserByID(id uint) (*models.User, error) {
+func (m *mockUserRepo) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)                             // Register the method call and return mocked arguments.
 	return args.Get(0).(*models.User), args.Error(1) // Return the mocked user and error.
 }
 
 // UpdateUser mocks the behavior of the UpdateUser method in the repository.
-func (m *mockUserRepo) UpdateUser(user *models.User) (*models.User, error) {
+func (m *mockUserRepo) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	args := m.Called(user)                           // Register the method call and return mocked arguments.
 	return args.Get(0).(*models.User), args.Error(1) // Return the mocked updated user and error.
 }
 
 // DeleteUser mocks the behavior of the DeleteUser method in the repository.
-func (m *mockUserRepo) DeleteUser(id uint) error {
+func (m *mockUserRepo) DeleteUser(ctx context.Context, id uint) error {
 	args := m.Called(id) // Register the method call and return mocked arguments.
 	return args.Error(0) // Return the mocked error.
 }
 
 // TestCreateUser tests the CreateUser method of the repository.
 func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 	// Create a dummy user object to simulate input.
 	user := &models.User{
@@ -73,7 +75,7 @@ func TestCreateUser(t *testing.T) {
 	mockRepo.On("CreateUser", user).Return(user, nil)
 
 	// Call the mocked CreateUser method.
-	createdUser, err := mockRepo.CreateUser(user)
+	createdUser, err := mockRepo.CreateUser(ctx, user)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err)                                    // Ensure no error was returned.
@@ -87,6 +89,7 @@ func TestCreateUser(t *testing.T) {
 
 // TestGetUserByEmail tests the GetUserByEmail method of the repository.
 func TestGetUserByEmail(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 
 	// Create a dummy user object to simulate the database record.
@@ -103,7 +106,7 @@ func TestGetUserByEmail(t *testing.T) {
 	mockRepo.On("GetUserByEmail", email).Return(user, nil)
 
 	// Call the mocked GetUserByEmail method.
-	fetchedUser, err := mockRepo.GetUserByEmail(email)
+	fetchedUser, err := mockRepo.GetUserByEmail(ctx, email)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err)                        // Ensure no error was returned.
@@ -117,6 +120,7 @@ func TestGetUserByEmail(t *testing.T) {
 
 // TestGetUserByID tests the GetUserByID method of the repository.
 func TestGetUserByID(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 
 	// Create a dummy user object to simulate the database record.
@@ -134,7 +138,7 @@ func TestGetUserByID(t *testing.T) {
 	mockRepo.On("GetUserByID", id).Return(user, nil)
 
 	// Call the mocked GetUserByID method.
-	fetchedUser, err := mockRepo.GetUserByID(id)
+	fetchedUser, err := mockRepo.GetUserByID(ctx, id)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err)                        // Ensure no error was returned.
@@ -148,6 +152,7 @@ func TestGetUserByID(t *testing.T) {
 
 // TestGetAllUsers tests the GetAllUsers method of the repository.
 func TestGetAllUsers(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 	// Create a dummy list of users to simulate the database records.
 	users := []models.User{
@@ -170,7 +175,7 @@ func TestGetAllUsers(t *testing.T) {
 	mockRepo.On("GetAllUsers").Return(users, nil)
 
 	// Call the mocked GetAllUsers method.
-	fetchedUsers, err := mockRepo.GetAllUsers()
+	fetchedUsers, err := mockRepo.GetAllUsers(ctx)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err)                            // Ensure no error was returned.
@@ -184,6 +189,7 @@ func TestGetAllUsers(t *testing.T) {
 
 // TestUpdateUser tests the UpdateUser method of the repository.
 func TestUpdateUser(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 
 	// Create a dummy user object to simulate the database record.
@@ -199,7 +205,7 @@ func TestUpdateUser(t *testing.T) {
 	mockRepo.On("UpdateUser", user).Return(user, nil)
 
 	// Call the mocked UpdateUser method.
-	updatedUser, err := mockRepo.UpdateUser(user)
+	updatedUser, err := mockRepo.UpdateUser(ctx, user)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err)                        // Ensure no error was returned.
@@ -212,6 +218,7 @@ func TestUpdateUser(t *testing.T) {
 
 // TestDeleteUser tests the DeleteUser method of the repository.
 func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(mockUserRepo) // Create a new instance of the mock repository.
 
 	// Define the user ID to delete.
@@ -221,7 +228,7 @@ func TestDeleteUser(t *testing.T) {
 	mockRepo.On("DeleteUser", id).Return(nil)
 
 	// Call the mocked DeleteUser method.
-	err := mockRepo.DeleteUser(id)
+	err := mockRepo.DeleteUser(ctx, id)
 
 	// Assertions to verify the behavior.
 	assert.NoError(t, err) // Ensure no error was returned.
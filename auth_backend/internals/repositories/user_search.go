@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/devesh121/userAuth/internals/models"
+)
+
+// maxSearchTerms bounds the words of a search query that are used
+const maxSearchTerms = 8
+
+// UserMatch is one user found by SearchUsers, with its relevance (higher is better)
+type UserMatch struct {
+	models.User
+	Rank float64
+}
+
+// SearchTerms splits a search query into the lower case words it matches on: runs of letters and
+// digits, so "ann@example" searches for "ann" and "example"
+func SearchTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !slices.Contains(terms, term) && len(terms) < maxSearchTerms {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// SearchUsers finds users by the words of their name and email, prefixes included, and by
+// substrings of them. PostgreSQL ranks with ts_rank plus trigram similarity (see migration 0002).
+func (r *postgresUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]UserMatch, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// Every word as a prefix: "ann exa" -> ann:* & exa:*
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsQuery := strings.Join(prefixes, " & ")
+	pattern := containsPattern(query)
+
+	var matches []UserMatch
+	err := r.db.WithContext(ctx).Model(&models.User{}).
+		Select("users.*, ts_rank(search_vector, to_tsquery('simple', ?)) + greatest(similarity(name, ?), similarity(email, ?)) AS rank", tsQuery, query, query).
+		Where(`search_vector @@ to_tsquery('simple', ?) OR name ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\'`, tsQuery, pattern, pattern).
+		Order("rank DESC").Order("id").
+		Limit(limit).
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// maxSearchCandidates bounds the rows the LIKE fallback ranks in Go
+const maxSearchCandidates = 1000
+
+// SearchUsers on SQLite has no full-text index: users containing every word in their name or
+// email are ranked in Go, the same way as in the in-memory store
+func (r *sqliteUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]UserMatch, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	db := r.db.WithContext(ctx)
+	for _, term := range terms {
+		pattern := containsPattern(term)
+		db = db.Where(`(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	var users []models.User
+	if err := db.Order("id").Limit(maxSearchCandidates).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return rankUsers(users, terms, limit), nil
+}
+
+// SearchUsers scans the users that aren't deleted, like the SQLite fallback
+func (r *memoryUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]UserMatch, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	users, err := r.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users = slices.DeleteFunc(users, func(user models.User) bool {
+		for _, term := range terms {
+			if !strings.Contains(strings.ToLower(user.Name), term) && !strings.Contains(strings.ToLower(user.Email), term) {
+				return true
+			}
+		}
+		return false
+	})
+	return rankUsers(users, terms, limit), nil
+}
+
+// rankUsers scores users for the fallbacks: a word that starts a word of the name counts most,
+// then one inside the name, then the same for the email. Best first, ties by ID.
+func rankUsers(users []models.User, terms []string, limit int) []UserMatch {
+	matches := make([]UserMatch, 0, len(users))
+	for _, user := range users {
+		rank := 0.0
+		for _, term := range terms {
+			rank += max(termScore(user.Name, term), 0.8*termScore(user.Email, term))
+		}
+		matches = append(matches, UserMatch{User: user, Rank: rank / float64(len(terms))})
+	}
+	slices.SortStableFunc(matches, func(a, b UserMatch) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// termScore is 1 when term is a whole word of value, 0.75 when it starts one, 0.5 when it is inside one
+func termScore(value, term string) float64 {
+	best := 0.0
+	for _, word := range SearchTerms(value) {
+		switch {
+		case word == term:
+			return 1
+		case strings.HasPrefix(word, term):
+			best = max(best, 0.75)
+		case strings.Contains(word, term):
+			best = max(best, 0.5)
+		}
+	}
+	return best
+}
@@ -0,0 +1,303 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/devesh121/userAuth/internals/cache"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/pkg/migrate"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newSQLiteDB opens a throwaway in-memory SQLite database with the embedded migrations applied
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrate.New(sqlDB, db.Dialector.Name())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+// userStores are the UserRepo implementations that work without PostgreSQL
+var userStores = map[string]func(t *testing.T) repositories.UserRepo{
+	"sqlite": func(t *testing.T) repositories.UserRepo { return repositories.NewSQLiteUserRepo(newSQLiteDB(t)) },
+	"memory": func(t *testing.T) repositories.UserRepo { return repositories.NewMemoryUserRepo() },
+	"cached-lru": func(t *testing.T) repositories.UserRepo {
+		return repositories.NewCachedUserRepo(repositories.NewSQLiteUserRepo(newSQLiteDB(t)), cache.NewLRU(100), time.Minute)
+	},
+	"cached-redis": func(t *testing.T) repositories.UserRepo {
+		store, err := cache.NewRedis("redis://" + miniredis.RunT(t).Addr())
+		require.NoError(t, err)
+		return repositories.NewCachedUserRepo(repositories.NewSQLiteUserRepo(newSQLiteDB(t)), store, time.Minute)
+	},
+}
+
+// TestUserStores runs the same checks against every UserRepo that works without PostgreSQL
+func TestUserStores(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range userStores {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+
+			ann, err := repo.CreateUser(ctx, &models.User{Name: "Ann", Email: "ann@example.com", Password: "hash", Age: 30})
+			require.NoError(t, err)
+			assert.NotZero(t, ann.ID)
+			assert.Equal(t, "user", ann.Role)
+			assert.EqualValues(t, 1, ann.Version)
+
+			bob, err := repo.CreateUser(ctx, &models.User{Name: "Bob", Email: "bob@example.com", Password: "hash", Age: 40, Role: "admin"})
+			require.NoError(t, err)
+
+			// Emails are unique, on create and on update
+			_, err = repo.CreateUser(ctx, &models.User{Name: "Ann 2", Email: "ann@example.com", Password: "hash"})
+			assert.ErrorIs(t, err, repositories.ErrDuplicateEmail)
+			bob.Email = "ann@example.com"
+			_, err = repo.UpdateUser(ctx, bob)
+			assert.ErrorIs(t, err, repositories.ErrDuplicateEmail)
+
+			found, err := repo.GetUserByEmail(ctx, "ann@example.com")
+			require.NoError(t, err)
+			assert.Equal(t, ann.ID, found.ID)
+			_, err = repo.GetUserByEmail(ctx, "nobody@example.com")
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+			ann.Name = "Ann B"
+			_, err = repo.UpdateUser(ctx, ann)
+			require.NoError(t, err)
+			found, err = repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Ann B", found.Name)
+			assert.EqualValues(t, 2, found.Version)
+
+			// An update made from an outdated copy is refused and changes nothing
+			stale := *found
+			found.Age = 31
+			_, err = repo.UpdateUser(ctx, found)
+			require.NoError(t, err)
+			stale.Name = "Ann C"
+			_, err = repo.UpdateUser(ctx, &stale)
+			assert.ErrorIs(t, err, repositories.ErrStaleUser)
+			found, err = repo.GetUserByID(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Ann B", found.Name)
+			assert.Equal(t, 31, found.Age)
+			assert.EqualValues(t, 3, found.Version)
+			ann = found
+
+			// Deleted users disappear and free their email
+			require.NoError(t, repo.DeleteUser(ctx, ann.ID))
+			_, err = repo.GetUserByID(ctx, ann.ID)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			users, err := repo.GetAllUsers(ctx)
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.Equal(t, "Bob", users[0].Name)
+			_, err = repo.CreateUser(ctx, &models.User{Name: "Ann 3", Email: "ann@example.com", Password: "hash"})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestUserStoresLifecycle checks listing, restoring and purging soft-deleted users on every store
+func TestUserStoresLifecycle(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range userStores {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			var users []*models.User
+			for _, u := range []models.User{
+				{Name: "Ann", Email: "ann@example.com", Age: 30},
+				{Name: "Bob", Email: "bob@example.com", Age: 40},
+				{Name: "Carl", Email: "carl@example.com", Age: 50},
+			} {
+				u.Password = "hash"
+				user, err := repo.CreateUser(ctx, &u)
+				require.NoError(t, err)
+				users = append(users, user)
+			}
+			ann, bob, carl := users[0], users[1], users[2]
+			for _, user := range users {
+				require.NoError(t, repo.DeleteUser(ctx, user.ID))
+				time.Sleep(time.Millisecond) // distinct deleted_at
+			}
+
+			// Most recently deleted first
+			deleted, total, err := repo.ListDeletedUsers(ctx, 2, 0)
+			require.NoError(t, err)
+			assert.EqualValues(t, 3, total)
+			require.Len(t, deleted, 2)
+			assert.Equal(t, []string{"Carl", "Bob"}, []string{deleted[0].Name, deleted[1].Name})
+
+			// Restoring brings the user back, unless a live user has the email now
+			restored, err := repo.RestoreUser(ctx, ann.ID)
+			require.NoError(t, err)
+			assert.False(t, restored.DeletedAt.Valid)
+			found, err := repo.GetUserByEmail(ctx, "ann@example.com")
+			require.NoError(t, err)
+			assert.EqualValues(t, 2, found.Version)
+			assert.Equal(t, restored.Version, found.Version)
+			_, err = repo.RestoreUser(ctx, ann.ID)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			_, err = repo.CreateUser(ctx, &models.User{Name: "Bob 2", Email: "bob@example.com", Password: "hash"})
+			require.NoError(t, err)
+			_, err = repo.RestoreUser(ctx, bob.ID)
+			assert.ErrorIs(t, err, repositories.ErrDuplicateEmail)
+
+			// Purge: Bob is anonymized, then Carl removed; neither can be listed or restored anymore
+			purged, err := repo.PurgeDeletedUsers(ctx, carl.CreatedAt.Add(time.Hour), repositories.PurgeAnonymize, 1)
+			require.NoError(t, err)
+			require.Len(t, purged, 1)
+			assert.Equal(t, "Bob", purged[0].Name)
+			purged, err = repo.PurgeDeletedUsers(ctx, carl.CreatedAt.Add(time.Hour), repositories.PurgeDelete, 0)
+			require.NoError(t, err)
+			require.Len(t, purged, 1)
+			assert.Equal(t, "Carl", purged[0].Name)
+
+			deleted, total, err = repo.ListDeletedUsers(ctx, 0, 0)
+			require.NoError(t, err)
+			assert.Empty(t, deleted)
+			assert.Zero(t, total)
+			for _, id := range []uint{bob.ID, carl.ID} {
+				_, err = repo.RestoreUser(ctx, id)
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			}
+			purged, err = repo.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour), repositories.PurgeDelete, 0)
+			require.NoError(t, err)
+			assert.Empty(t, purged)
+		})
+	}
+}
+
+// TestUserStoresList checks filters, sort order and keyset paging of ListUsers on every store
+func TestUserStoresList(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range userStores {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, u := range []models.User{
+				{Name: "Ann", Email: "ann@example.com", Age: 30, Role: "admin"},
+				{Name: "Bob", Email: "bob@corp.test", Age: 40},
+				{Name: "Carl", Email: "carl@example.com", Age: 30},
+				{Name: "Dana", Email: "dana_x@example.com", Age: 25},
+			} {
+				u.Password = "hash"
+				_, err := repo.CreateUser(ctx, &u)
+				require.NoError(t, err)
+			}
+			names := func(query repositories.UserListQuery) ([]string, int64) {
+				users, total, err := repo.ListUsers(ctx, query)
+				require.NoError(t, err)
+				var names []string
+				for _, u := range users {
+					names = append(names, u.Name)
+				}
+				return names, total
+			}
+
+			// Filters, with LIKE wildcards in the input taken literally
+			minAge, maxAge := 26, 35
+			got, total := names(repositories.UserListQuery{Filter: repositories.UserFilter{MinAge: &minAge, MaxAge: &maxAge}})
+			assert.Equal(t, []string{"Ann", "Carl"}, got)
+			assert.EqualValues(t, 2, total)
+			got, _ = names(repositories.UserListQuery{Filter: repositories.UserFilter{Email: "EXAMPLE.com", Role: "user"}})
+			assert.Equal(t, []string{"Carl", "Dana"}, got)
+			got, _ = names(repositories.UserListQuery{Filter: repositories.UserFilter{Email: "_x"}})
+			assert.Equal(t, []string{"Dana"}, got)
+
+			// Sort by age descending, ties by ID descending, paged by offset and by key
+			got, total = names(repositories.UserListQuery{SortBy: "age", Desc: true, Limit: 2})
+			assert.Equal(t, []string{"Bob", "Carl"}, got)
+			assert.EqualValues(t, 4, total)
+			got, _ = names(repositories.UserListQuery{SortBy: "age", Desc: true, Limit: 2, Offset: 2})
+			assert.Equal(t, []string{"Ann", "Dana"}, got)
+			carl, err := repo.GetUserByEmail(ctx, "carl@example.com")
+			require.NoError(t, err)
+			got, _ = names(repositories.UserListQuery{SortBy: "age", Desc: true, After: &repositories.UserKey{Value: 30, ID: carl.ID}})
+			assert.Equal(t, []string{"Ann", "Dana"}, got)
+
+			_, _, err = repo.ListUsers(ctx, repositories.UserListQuery{SortBy: "password"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestUserStoresSearch checks the LIKE fallback of SearchUsers: every word must match, best match first
+func TestUserStoresSearch(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range userStores {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			for _, u := range []models.User{
+				{Name: "Joanna Smith", Email: "jo@example.com"},
+				{Name: "Jo Brown", Email: "brown@corp.test"},
+				{Name: "Anna Jones", Email: "anna.jones@example.com"},
+			} {
+				u.Password = "hash"
+				_, err := repo.CreateUser(ctx, &u)
+				require.NoError(t, err)
+			}
+			search := func(query string) []string {
+				matches, err := repo.SearchUsers(ctx, query, 10)
+				require.NoError(t, err)
+				var names []string
+				for _, m := range matches {
+					names = append(names, m.Name)
+				}
+				return names
+			}
+
+			// Whole word before prefix before substring
+			assert.Equal(t, []string{"Jo Brown", "Joanna Smith", "Anna Jones"}, search("jo"))
+			assert.Equal(t, []string{"Anna Jones"}, search("jones@EXAMPLE"))
+			assert.Equal(t, []string{"Anna Jones", "Joanna Smith"}, search("anna"))
+			assert.Empty(t, search("nobody"))
+			assert.Empty(t, search("%_"))
+		})
+	}
+}
+
+// TestPurgeRemovesPersonalData checks that purged users lose their credentials and login history,
+// also when the users themselves are kept in memory
+func TestPurgeRemovesPersonalData(t *testing.T) {
+	stores := map[string]func(db *gorm.DB) repositories.UserRepo{
+		"sqlite": repositories.NewSQLiteUserRepo,
+		"memory": repositories.NewMemoryUserRepoOn,
+	}
+	for name, newRepo := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newSQLiteDB(t)
+			repo := newRepo(db)
+
+			ann, err := repo.CreateUser(ctx, &models.User{Name: "Ann", Email: "ann@example.com", Password: "hash", Age: 30})
+			require.NoError(t, err)
+			require.NoError(t, db.Create(&models.LoginAttempt{UserID: ann.ID, Success: true, IP: "192.0.2.10", Network: "192.0.2.0/24", UserAgent: "curl"}).Error)
+			require.NoError(t, db.Create(&models.APIKey{UserID: ann.ID, Name: "ci", Prefix: "ak_test", KeyHash: "hash"}).Error)
+			require.NoError(t, repo.DeleteUser(ctx, ann.ID))
+
+			purged, err := repo.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour), repositories.PurgeAnonymize, 0)
+			require.NoError(t, err)
+			require.Len(t, purged, 1)
+			for _, model := range []any{&models.LoginAttempt{}, &models.APIKey{}} {
+				var count int64
+				require.NoError(t, db.Unscoped().Model(model).Where("user_id = ?", ann.ID).Count(&count).Error)
+				assert.Zero(t, count, "%T", model)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// internals/routes/admin_routes.go
+package routes
+
+import (
+	"context"
+
+	"github.com/devesh121/userAuth/internals/controllers"
+	"github.com/devesh121/userAuth/internals/middlewares"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRoutes registers the endpoints reserved to the admin role
+func AdminRoutes(v1 *gin.RouterGroup) {
+	admin := v1.Group("/admin")
+
+	db := config.DB
+
+	auditService := newAuditService(db)
+	auditController := controllers.NewAuditController(auditService)
+
+	userRepo := newUserRepo(db)
+	userService := services.NewUserService(userRepo, newSessionService(db), auditService, newLoginHistoryService(db), newEmailVerificationService(db, userRepo), newAPIKeyService(db, userRepo), authenticators(userRepo, auditService)...)
+	userController := controllers.NewUserController(userService)
+
+	admin.Use(authMiddlewares(db)...)
+	admin.Use(middlewares.RequireRole(auditService, "admin"))
+	{
+		admin.GET("/audit-events", auditController.ListAuditEvents)
+		admin.GET("/audit-events/verify", auditController.VerifyAuditChain)
+		admin.GET("/audit-events/export", auditController.ExportAuditEvents)
+		admin.POST("/audit-checkpoints", auditController.CreateCheckpoint)
+
+		// Soft-deleted users, restorable until purged
+		admin.GET("/users/deleted", userController.ListDeletedUsers)
+		admin.POST("/users/:id/restore", userController.RestoreUser)
+	}
+}
+
+// StartUserPurge deletes or anonymizes, in the background, the users deleted longer ago than
+// USER_RETENTION_DAYS
+func StartUserPurge() {
+	db := config.DB
+	purgeService := services.NewUserPurgeService(newUserRepo(db), newAuditService(db), config.GetUserPurgeConfig())
+	go purgeService.RunPurges(context.Background())
+}
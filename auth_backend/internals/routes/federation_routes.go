@@ -0,0 +1,41 @@
+// internals/routes/federation_routes.go
+package routes
+
+import (
+	"context"
+	"log"
+
+	"github.com/devesh121/userAuth/internals/controllers"
+	"github.com/devesh121/userAuth/internals/providers"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// FederationRoutes registers login through the configured upstream identity providers
+func FederationRoutes(v1 *gin.RouterGroup) {
+	auth := v1.Group("/auth")
+
+	db := config.DB
+
+	// A provider that can't be set up (e.g. failed discovery) is skipped so the rest of the API still starts
+	var list []providers.Provider
+	for _, cfg := range config.GetFederationProviders() {
+		provider, err := providers.NewProvider(context.Background(), cfg)
+		if err != nil {
+			log.Printf("⚠️  Skipping identity provider %s: %v", cfg.Name, err)
+			continue
+		}
+		list = append(list, provider)
+	}
+
+	userRepo := newUserRepo(db)
+	identityRepo := repositories.NewPostgresIdentityRepo(db)
+	sessionService := newSessionService(db)
+	federationService := services.NewFederationService(userRepo, identityRepo, sessionService, newLoginHistoryService(db), newAuditService(db), list)
+	federationController := controllers.NewFederationController(federationService)
+
+	auth.GET("/:provider/login", federationController.Login)
+	auth.GET("/:provider/callback", federationController.Callback)
+}
@@ -0,0 +1,25 @@
+// internals/routes/oauth_routes.go
+package routes
+
+import (
+	"github.com/devesh121/userAuth/internals/controllers"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthRoutes registers the RFC 7662 / RFC 7009 endpoints used by resource servers
+func OAuthRoutes(r *gin.RouterGroup) {
+	oauth := r.Group("/oauth")
+
+	db := config.DB
+
+	tokenRepo := repositories.NewPostgresTokenRepo(db)
+	oauthService := services.NewOAuthService(tokenRepo, newSessionService(db), config.GetOAuthClients())
+	oauthController := controllers.NewOAuthController(oauthService)
+
+	// Authenticated with client credentials, not user tokens
+	oauth.POST("/introspect", oauthController.IntrospectToken)
+	oauth.POST("/revoke", oauthController.RevokeToken)
+}
@@ -0,0 +1,38 @@
+// internals/routes/saml_routes.go
+package routes
+
+import (
+	"log"
+
+	"github.com/devesh121/userAuth/internals/controllers"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// SAMLRoutes registers the SAML service provider endpoints when SAML is configured
+func SAMLRoutes(v1 *gin.RouterGroup) {
+	cfg := config.GetSAMLConfig()
+	if !cfg.Enabled() {
+		return
+	}
+
+	sp, err := services.NewSAMLServiceProvider(cfg)
+	if err != nil {
+		log.Printf("⚠️  SAML disabled: %v", err)
+		return
+	}
+
+	samlGroup := v1.Group("/saml")
+
+	db := config.DB
+
+	userRepo := newUserRepo(db)
+	sessionService := newSessionService(db)
+	samlService := services.NewSAMLService(sp, cfg, userRepo, sessionService, newLoginHistoryService(db), newAuditService(db))
+	samlController := controllers.NewSAMLController(samlService)
+
+	samlGroup.GET("/metadata", samlController.Metadata)
+	samlGroup.GET("/login", samlController.Login)
+	samlGroup.POST("/acs", samlController.ACS)
+}
@@ -2,12 +2,21 @@
 package routes
 
 import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/cache"
 	"github.com/devesh121/userAuth/internals/controllers"
+	"github.com/devesh121/userAuth/internals/geoip"
+	"github.com/devesh121/userAuth/internals/mailer"
 	"github.com/devesh121/userAuth/internals/middlewares"
 	"github.com/devesh121/userAuth/internals/repositories"
 	"github.com/devesh121/userAuth/internals/services"
 	"github.com/devesh121/userAuth/pkg/config"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func UserRoutes(v1 *gin.RouterGroup) {
@@ -15,23 +24,173 @@ func UserRoutes(v1 *gin.RouterGroup) {
 
 	db := config.DB
 
-	userRepo := repositories.NewPostgresUserRepo(db)
-	userService := services.NewUserService(userRepo)
+	userRepo := newUserRepo(db)
+	auditService := newAuditService(db)
+	sessionService := newSessionService(db)
+	historyService := newLoginHistoryService(db)
+	emailService := newEmailVerificationService(db, userRepo)
+	apiKeyService := newAPIKeyService(db, userRepo)
+	userService := services.NewUserService(userRepo, sessionService, auditService, historyService, emailService, apiKeyService, authenticators(userRepo, auditService)...)
 	userController := controllers.NewUserController(userService)
+	emailController := controllers.NewEmailVerificationController(emailService)
+	sessionController := controllers.NewSessionController(sessionService)
+	historyController := controllers.NewLoginHistoryController(historyService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
 
 	// Public routes
 	users.POST("/register", userController.RegisterUser)
 	users.POST("/login", userController.LoginUser)
 	users.POST("/logout", userController.LogoutUser)
+	// Mailed links only show a page, its button posts the token to apply the link
+	users.GET("/verify-email", emailController.VerifyEmailPage)
+	users.POST("/verify-email", emailController.VerifyEmail)
+	users.GET("/confirm-email", emailController.ConfirmEmailPage)
+	users.POST("/confirm-email", emailController.ConfirmEmail)
+	users.GET("/revert-email", emailController.RevertEmailPage)
+	users.POST("/revert-email", emailController.RevertEmail)
 
 	// Protected routes
 	protected := users.Group("/")
-	protected.Use(middlewares.JWTAuthMiddleware())
+	protected.Use(authMiddlewares(db)...)
 	{
 		protected.GET("/", userController.GetAllUsers)
+		protected.GET("/search", userController.SearchUsers)
 		protected.GET("/:id", userController.GetUserByID)
 		protected.POST("/email", userController.GetUserByEmail)
-		protected.PUT("/:id", userController.UpdateUserByID)
-		protected.DELETE("/:id", userController.DeleteUserByID)
+		// A replace or patch can set the password or the email, so only the owner or an admin
+		// may send it, or delete the account
+		ownerOnly := middlewares.RequireSelfOrRole(auditService, "id", "admin")
+		protected.PUT("/:id", ownerOnly, userController.UpdateUserByID)
+		protected.PATCH("/:id", ownerOnly, userController.PatchUserByID)
+		protected.DELETE("/:id", ownerOnly, userController.DeleteUserByID)
+
+		// Personal API keys of the logged in user, managed with a login session only
+		sessionOnly := middlewares.RequireSession(auditService)
+		protected.POST("/me/api-keys", sessionOnly, apiKeyController.CreateAPIKey)
+		protected.GET("/me/api-keys", sessionOnly, apiKeyController.ListAPIKeys)
+		protected.DELETE("/me/api-keys/:key_id", sessionOnly, apiKeyController.RevokeAPIKey)
+
+		// Login sessions of the logged in user
+		protected.GET("/me/sessions", sessionController.ListSessions)
+		protected.DELETE("/me/sessions", sessionController.RevokeOtherSessions)
+		protected.DELETE("/me/sessions/:session_id", sessionController.RevokeSession)
+
+		// Login history of the logged in user
+		protected.GET("/me/logins", historyController.ListLogins)
+	}
+}
+
+// StartActiveUsersGauge counts, in the background and every minute, the users with a live
+// session into metrics.ActiveUsers
+func StartActiveUsersGauge() {
+	go newSessionService(config.DB).RunActiveUsersGauge(context.Background(), time.Minute)
+}
+
+// authMiddlewares returns the middlewares of protected routes: API key or JWT (header / cookie)
+func authMiddlewares(db *gorm.DB) []gin.HandlerFunc {
+	auditService := newAuditService(db)
+	return []gin.HandlerFunc{
+		middlewares.APIKeyAuthMiddleware(newAPIKeyService(db, newUserRepo(db)), auditService),
+		middlewares.JWTAuthMiddleware(middlewares.AuthConfig{
+			TokenRepo:   repositories.NewPostgresTokenRepo(db),
+			Sessions:    newSessionService(db),
+			Audit:       auditService,
+			TokenLookup: config.GetTokenLookup(),
+		}),
+	}
+}
+
+// newUserRepo returns the user store selected by USER_STORE / DB_DRIVER, behind the cache
+// selected by USER_CACHE
+func newUserRepo(db *gorm.DB) repositories.UserRepo {
+	var repo repositories.UserRepo
+	switch config.GetUserStore() {
+	case "memory":
+		return memoryUserRepo() // nothing to save by caching it
+	case "sqlite":
+		repo = repositories.NewSQLiteUserRepo(db)
+	default:
+		repo = repositories.NewPostgresUserRepo(db)
+	}
+	if store := userCacheStore(); store != nil {
+		repo = repositories.NewCachedUserRepo(repo, store, config.GetUserCacheConfig().TTL)
+	}
+	return repo
+}
+
+// userCacheStore is the one user cache shared by all route groups, so that a change made
+// through one drops the user for all of them. nil when USER_CACHE is off.
+var userCacheStore = sync.OnceValue(func() cache.Store {
+	store, err := cache.NewStore(config.GetUserCacheConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure user cache: %v", err)
+	}
+	return store
+})
+
+// memoryUserRepo is the one in-memory user store shared by all route groups. User IDs start again
+// at 1 on restart, so the API keys, linked identities and sessions of a database that outlives
+// the process would sign in as whoever gets their owner's ID next: the database must go too.
+var memoryUserRepo = sync.OnceValue(func() repositories.UserRepo {
+	if dbConfig, err := config.GetDBConfig(); err != nil || !dbConfig.Throwaway() {
+		log.Fatal("USER_STORE=memory needs a database that is lost on restart too: DB_DRIVER=sqlite and SQLITE_PATH=:memory:")
+	}
+	log.Println("⚠️  USER_STORE=memory: user accounts are lost on restart")
+	return repositories.NewMemoryUserRepoOn(config.DB)
+})
+
+// newAuditService builds the audit log service
+func newAuditService(db *gorm.DB) services.AuditService {
+	return services.NewAuditService(repositories.NewPostgresAuditRepo(db), config.GetAuditConfig())
+}
+
+// newSessionService builds the session service with the configured concurrent session limits
+func newSessionService(db *gorm.DB) services.SessionService {
+	return services.NewSessionService(repositories.NewPostgresSessionRepo(db), config.GetSessionLimitConfig(), newAuditService(db))
+}
+
+// newAPIKeyService builds the personal API key service
+func newAPIKeyService(db *gorm.DB, userRepo repositories.UserRepo) services.APIKeyService {
+	return services.NewAPIKeyService(repositories.NewPostgresAPIKeyRepo(db), userRepo)
+}
+
+// newLoginHistoryService builds the login history service with the GeoIP database and mailer
+func newLoginHistoryService(db *gorm.DB) services.LoginHistoryService {
+	locator, mail := loginAlertDeps()
+	return services.NewLoginHistoryService(repositories.NewPostgresLoginHistoryRepo(db), locator, mail)
+}
+
+// newEmailVerificationService builds the service mailing email verification and change links
+func newEmailVerificationService(db *gorm.DB, userRepo repositories.UserRepo) services.EmailVerificationService {
+	_, mail := loginAlertDeps()
+	return services.NewEmailVerificationService(userRepo, newSessionService(db), newAuditService(db), mail, config.GetPublicURL())
+}
+
+// loginAlertDeps opens the GeoIP database and the mailer once for all route groups
+var loginAlertDeps = sync.OnceValues(func() (geoip.Locator, mailer.Mailer) {
+	locator, err := geoip.NewLocator(config.GetGeoIPDBPath())
+	if err != nil {
+		log.Fatalf("Failed to open GeoIP database: %v", err)
+	}
+	mail, err := mailer.NewMailer(config.GetMailerConfig())
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	return locator, mail
+})
+
+// authenticators builds the login strategies listed in AUTH_STRATEGIES
+func authenticators(userRepo repositories.UserRepo, audit services.AuditService) []services.Authenticator {
+	var list []services.Authenticator
+	for _, strategy := range config.GetAuthStrategies() {
+		switch strategy {
+		case "local":
+			list = append(list, services.NewLocalAuthenticator(repositories.Uncached(userRepo))) // the cache keeps no password hashes
+		case "ldap":
+			list = append(list, services.NewLDAPAuthenticator(config.GetLDAPConfig(), userRepo, audit))
+		default:
+			log.Printf("⚠️  Unknown auth strategy %q ignored", strategy)
+		}
 	}
+	return list
 }
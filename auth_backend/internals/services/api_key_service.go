@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every personal API key, so keys are easy to recognise (and to scan for in leaks)
+const APIKeyPrefix = "ak_"
+
+// Errors of the api key endpoints and of APIKeyAuthMiddleware
+var (
+	ErrAPIKeyNotFound = NewError(ErrNotFound, "api_key_not_found", "api key not found")
+	ErrAPIKeyInvalid  = NewError(ErrUnauthorized, "api_key_invalid", "invalid api key")
+	ErrAPIKeyRevoked  = NewError(ErrUnauthorized, "api_key_revoked", "api key revoked")
+	ErrAPIKeyExpired  = NewError(ErrUnauthorized, "api_key_expired", "api key expired")
+)
+
+// APIKeyService manages personal API keys and authenticates requests made with them
+type APIKeyService interface {
+	CreateAPIKeyService(ctx context.Context, userID uint, req dto.CreateAPIKeyRequest) (*dto.APIKeyCreatedResponse, error)
+	ListAPIKeysService(ctx context.Context, userID uint) ([]dto.APIKeyResponse, error)
+	RevokeAPIKeyService(ctx context.Context, userID, keyID uint) error
+	RevokeUserAPIKeysService(ctx context.Context, userID uint) (int64, error)
+	AuthenticateAPIKeyService(ctx context.Context, rawKey, ip string) (*models.User, *models.APIKey, error)
+}
+
+// apiKeyServiceImpl struct implements the APIKeyService interface
+type apiKeyServiceImpl struct {
+	apiKeyRepo repositories.APIKeyRepo
+	userRepo   repositories.UserRepo
+}
+
+// NewAPIKeyService returns implementation of APIKeyService
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepo, userRepo repositories.UserRepo) APIKeyService {
+	return &apiKeyServiceImpl{apiKeyRepo: apiKeyRepo, userRepo: userRepo}
+}
+
+// CreateAPIKeyService generates a new key; the secret is returned only here
+func (s *apiKeyServiceImpl) CreateAPIKeyService(ctx context.Context, userID uint, req dto.CreateAPIKeyRequest) (*dto.APIKeyCreatedResponse, error) {
+	// Step 1: Generate "ak_<8 char id>_<secret>"
+	id, err := utils.RandomString(6)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	prefix := APIKeyPrefix + id
+	rawKey := prefix + "_" + secret
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read"}
+	}
+
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hashAPIKey(rawKey),
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	// Step 2: Store only the hash
+	created, err := s.apiKeyRepo.CreateAPIKey(ctx, key)
+	if err != nil {
+		return nil, errors.New("failed to create api key")
+	}
+
+	return &dto.APIKeyCreatedResponse{
+		APIKeyResponse: toAPIKeyResponse(created),
+		Key:            rawKey,
+	}, nil
+}
+
+// ListAPIKeysService returns the keys of a user without their secrets
+func (s *apiKeyServiceImpl) ListAPIKeysService(ctx context.Context, userID uint) ([]dto.APIKeyResponse, error) {
+	keys, err := s.apiKeyRepo.ListAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, toAPIKeyResponse(&keys[i]))
+	}
+	return responses, nil
+}
+
+// RevokeAPIKeyService revokes one of the user's keys
+func (s *apiKeyServiceImpl) RevokeAPIKeyService(ctx context.Context, userID, keyID uint) error {
+	key, err := s.apiKeyRepo.GetAPIKeyByID(ctx, keyID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil || key.UserID != userID {
+		// Don't reveal keys of other users
+		return ErrAPIKeyNotFound
+	}
+	return s.apiKeyRepo.RevokeAPIKey(ctx, key.ID, time.Now())
+}
+
+// RevokeUserAPIKeysService revokes all keys of a user, e.g. when the user is deleted, and returns
+// how many were live
+func (s *apiKeyServiceImpl) RevokeUserAPIKeysService(ctx context.Context, userID uint) (int64, error) {
+	return s.apiKeyRepo.RevokeAPIKeysByUser(ctx, userID, time.Now())
+}
+
+// AuthenticateAPIKeyService checks a presented key and records its usage
+func (s *apiKeyServiceImpl) AuthenticateAPIKeyService(ctx context.Context, rawKey, ip string) (*models.User, *models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, nil, ErrAPIKeyRevoked
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, nil, ErrAPIKeyExpired
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+
+	// Usage tracking must not fail the request
+	if err := s.apiKeyRepo.RecordAPIKeyUsage(ctx, key.ID, now, ip); err != nil {
+		log.Printf("failed to record api key usage for key %d: %v", key.ID, err)
+	}
+
+	return user, key, nil
+}
+
+// hashAPIKey returns the hex SHA-256 of a key; keys are long random strings, so a fast hash is enough
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// toAPIKeyResponse maps the model to the response DTO
+func toAPIKeyResponse(key *models.APIKey) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		RevokedAt:  key.RevokedAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+	}
+}
@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeAPIKeyRepo is an in-memory APIKeyRepo
+type fakeAPIKeyRepo struct {
+	mu   sync.Mutex
+	keys []models.APIKey
+}
+
+func (r *fakeAPIKeyRepo) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = uint(len(r.keys) + 1)
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, *key)
+	return key, nil
+}
+
+func (r *fakeAPIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return r.find(func(key *models.APIKey) bool { return key.KeyHash == hash })
+}
+
+func (r *fakeAPIKeyRepo) GetAPIKeyByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	return r.find(func(key *models.APIKey) bool { return key.ID == id })
+}
+
+func (r *fakeAPIKeyRepo) ListAPIKeysByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []models.APIKey
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i].UserID == userID {
+			keys = append(keys, r.keys[i])
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepo) RevokeAPIKey(ctx context.Context, id uint, at time.Time) error {
+	r.update(func(key *models.APIKey) bool { return key.ID == id && key.RevokedAt == nil }, func(key *models.APIKey) { key.RevokedAt = &at })
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) RevokeAPIKeysByUser(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	return r.update(func(key *models.APIKey) bool { return key.UserID == userID && key.RevokedAt == nil }, func(key *models.APIKey) { key.RevokedAt = &at }), nil
+}
+
+func (r *fakeAPIKeyRepo) RecordAPIKeyUsage(ctx context.Context, id uint, at time.Time, ip string) error {
+	r.update(func(key *models.APIKey) bool { return key.ID == id }, func(key *models.APIKey) {
+		key.LastUsedAt = &at
+		key.LastUsedIP = ip
+	})
+	return nil
+}
+
+// find returns a copy of the first key matching
+func (r *fakeAPIKeyRepo) find(match func(*models.APIKey) bool) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.keys {
+		if match(&r.keys[i]) {
+			key := r.keys[i]
+			return &key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// update changes the keys matching and returns how many there were
+func (r *fakeAPIKeyRepo) update(match func(*models.APIKey) bool, change func(*models.APIKey)) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for i := range r.keys {
+		if match(&r.keys[i]) {
+			change(&r.keys[i])
+			n++
+		}
+	}
+	return n
+}
+
+// newTestAPIKeyService returns an API key service with an in-memory key store
+func newTestAPIKeyService(userRepo repositories.UserRepo) services.APIKeyService {
+	return services.NewAPIKeyService(&fakeAPIKeyRepo{}, userRepo)
+}
+
+// TestAPIKeyLifecycle checks creating, listing, using and revoking keys
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	users := repositories.NewMemoryUserRepo()
+	ann, err := users.CreateUser(ctx, &models.User{Name: "Ann", Email: "ann@example.com", Password: "hash"})
+	require.NoError(t, err)
+	repo := &fakeAPIKeyRepo{}
+	apiKeys := services.NewAPIKeyService(repo, users)
+
+	// The secret is only returned on creation, the store keeps its SHA-256
+	created, err := apiKeys.CreateAPIKeyService(ctx, ann.ID, dto.CreateAPIKeyRequest{Name: "ci"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix+"_"))
+	assert.Equal(t, []string{"read"}, created.Scopes, "read only by default")
+	assert.Nil(t, created.ExpiresAt)
+	sum := sha256.Sum256([]byte(created.Key))
+	assert.Equal(t, hex.EncodeToString(sum[:]), repo.keys[0].KeyHash)
+
+	// Keys are found by the hash of the presented key, and their use is recorded
+	user, key, err := apiKeys.AuthenticateAPIKeyService(ctx, created.Key, "192.0.2.10")
+	require.NoError(t, err)
+	assert.Equal(t, ann.ID, user.ID)
+	assert.Equal(t, created.ID, key.ID)
+	for _, wrong := range []string{"", "not-a-key", created.Key + "x", created.Prefix + "_guess"} {
+		_, _, err := apiKeys.AuthenticateAPIKeyService(ctx, wrong, "192.0.2.10")
+		assert.ErrorIs(t, err, services.ErrAPIKeyInvalid, wrong)
+	}
+
+	listed, err := apiKeys.ListAPIKeysService(ctx, ann.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "192.0.2.10", listed[0].LastUsedIP)
+	assert.NotNil(t, listed[0].LastUsedAt)
+	listed, err = apiKeys.ListAPIKeysService(ctx, ann.ID+1)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// Only the owner can revoke a key, which then stops working
+	assert.ErrorIs(t, apiKeys.RevokeAPIKeyService(ctx, ann.ID+1, created.ID), services.ErrAPIKeyNotFound)
+	assert.ErrorIs(t, apiKeys.RevokeAPIKeyService(ctx, ann.ID, created.ID+1), services.ErrAPIKeyNotFound)
+	require.NoError(t, apiKeys.RevokeAPIKeyService(ctx, ann.ID, created.ID))
+	_, _, err = apiKeys.AuthenticateAPIKeyService(ctx, created.Key, "192.0.2.10")
+	assert.ErrorIs(t, err, services.ErrAPIKeyRevoked)
+}
+
+// TestAPIKeyExpiry checks that a key stops working once expired
+func TestAPIKeyExpiry(t *testing.T) {
+	ctx := context.Background()
+	users := repositories.NewMemoryUserRepo()
+	ann, err := users.CreateUser(ctx, &models.User{Name: "Ann", Email: "ann@example.com", Password: "hash"})
+	require.NoError(t, err)
+	repo := &fakeAPIKeyRepo{}
+	apiKeys := services.NewAPIKeyService(repo, users)
+
+	created, err := apiKeys.CreateAPIKeyService(ctx, ann.ID, dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read", "write"}, ExpiresInDays: 30})
+	require.NoError(t, err)
+	require.NotNil(t, created.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *created.ExpiresAt, time.Minute)
+	_, _, err = apiKeys.AuthenticateAPIKeyService(ctx, created.Key, "192.0.2.10")
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Second)
+	repo.keys[0].ExpiresAt = &expired
+	_, _, err = apiKeys.AuthenticateAPIKeyService(ctx, created.Key, "192.0.2.10")
+	assert.ErrorIs(t, err, services.ErrAPIKeyExpired)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"gorm.io/gorm"
+)
+
+// auditBatchSize is how many events are read at a time when walking the chain
+const auditBatchSize = 500
+
+// ErrAuditSigningDisabled is returned when a checkpoint is requested without a signing key
+var ErrAuditSigningDisabled = NewError(ErrConflict, "audit_signing_disabled", "audit checkpoints are disabled: AUDIT_SIGNING_KEY is not set")
+
+// CheckpointService signs the current head of the chain. It returns nil when nothing
+// was appended since the last checkpoint.
+func (s *auditServiceImpl) CheckpointService(ctx context.Context) (*dto.AuditCheckpointResponse, error) {
+	if s.cfg.SigningKey == nil {
+		return nil, ErrAuditSigningDisabled
+	}
+
+	// Step 1: Find the head of the chain
+	head, err := s.auditRepo.LastEvent(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Skip if it is already covered
+	last, err := s.auditRepo.LastCheckpoint(ctx)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if last != nil && last.EventID == head.ID {
+		return nil, nil
+	}
+
+	// Step 3: Sign and store
+	checkpoint := &models.AuditCheckpoint{
+		EventID:   head.ID,
+		EventHash: head.Hash,
+		KeyID:     keyID(s.cfg.SigningKey.Public().(ed25519.PublicKey)),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.cfg.SigningKey, checkpoint.SignedPayload()))
+	if err := s.auditRepo.AppendCheckpoint(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return &dto.AuditCheckpointResponse{
+		ID:        checkpoint.ID,
+		EventID:   checkpoint.EventID,
+		EventHash: checkpoint.EventHash,
+		KeyID:     checkpoint.KeyID,
+		Signature: checkpoint.Signature,
+		CreatedAt: checkpoint.CreatedAt,
+	}, nil
+}
+
+// RunCheckpoints writes a checkpoint every CheckpointInterval until ctx is done
+func (s *auditServiceImpl) RunCheckpoints(ctx context.Context) {
+	if s.cfg.SigningKey == nil {
+		log.Println("audit checkpoints disabled: AUDIT_SIGNING_KEY is not set")
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CheckpointService(ctx); err != nil {
+				log.Printf("failed to write audit checkpoint: %v", err)
+			}
+		}
+	}
+}
+
+// VerifyChainService walks the whole chain and reports the first broken link.
+// Edited events break their own hash, removed or reordered events break the next PrevHash,
+// and events removed from the end are caught by the checkpoints that covered them.
+func (s *auditServiceImpl) VerifyChainService(ctx context.Context) (*dto.AuditVerifyResult, error) {
+	checkpoints, err := s.auditRepo.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byEvent := make(map[uint][]models.AuditCheckpoint)
+	for _, checkpoint := range checkpoints {
+		byEvent[checkpoint.EventID] = append(byEvent[checkpoint.EventID], checkpoint)
+	}
+
+	result := &dto.AuditVerifyResult{Valid: true}
+	prevHash := ""
+	var lastID uint
+	for {
+		events, err := s.auditRepo.ListEventsAfter(ctx, lastID, auditBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for _, event := range events {
+			if event.PrevHash != prevHash {
+				return brokenEvent(result, event.ID, "prev_hash doesn't match the previous event: events before it were removed, inserted or reordered"), nil
+			}
+			if event.ChainHash() != event.Hash {
+				return brokenEvent(result, event.ID, "hash doesn't match the content: the event was modified"), nil
+			}
+
+			// Checkpoints signed at this event
+			for _, checkpoint := range byEvent[event.ID] {
+				if reason := s.checkCheckpoint(checkpoint, event, result); reason != "" {
+					return brokenCheckpoint(result, checkpoint.ID, reason), nil
+				}
+			}
+			delete(byEvent, event.ID)
+
+			prevHash = event.Hash
+			lastID = event.ID
+			result.EventsChecked++
+			result.HeadHash = event.Hash
+		}
+	}
+
+	// Checkpoints left over point at events that are gone
+	for _, checkpoint := range checkpoints {
+		if _, missing := byEvent[checkpoint.EventID]; missing {
+			return brokenCheckpoint(result, checkpoint.ID,
+				fmt.Sprintf("covers event %d which no longer exists: events were removed", checkpoint.EventID)), nil
+		}
+	}
+	return result, nil
+}
+
+// checkCheckpoint verifies one checkpoint against the event it covers, returning why it is broken
+func (s *auditServiceImpl) checkCheckpoint(checkpoint models.AuditCheckpoint, event models.AuditEvent, result *dto.AuditVerifyResult) string {
+	if checkpoint.EventHash != event.Hash {
+		return fmt.Sprintf("signed hash of event %d differs from the chain", event.ID)
+	}
+	result.CheckpointsChecked++
+
+	if s.cfg.PublicKey == nil {
+		result.CheckpointsUnverified++
+		return ""
+	}
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil || !ed25519.Verify(s.cfg.PublicKey, checkpoint.SignedPayload(), signature) {
+		return "signature is invalid"
+	}
+	return ""
+}
+
+// ExportService writes the chain as JSON Lines, one event per line in chain order
+func (s *auditServiceImpl) ExportService(ctx context.Context, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	var lastID uint
+	for {
+		events, err := s.auditRepo.ListEventsAfter(ctx, lastID, auditBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, event := range events {
+			if err := encoder.Encode(toAuditEventResponse(event)); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+	}
+}
+
+// brokenEvent marks the result as failed at an event
+func brokenEvent(result *dto.AuditVerifyResult, eventID uint, reason string) *dto.AuditVerifyResult {
+	result.Valid = false
+	result.FirstBrokenEventID = eventID
+	result.Reason = reason
+	return result
+}
+
+// brokenCheckpoint marks the result as failed at a checkpoint
+func brokenCheckpoint(result *dto.AuditVerifyResult, checkpointID uint, reason string) *dto.AuditVerifyResult {
+	result.Valid = false
+	result.FirstBrokenCheckpoint = checkpointID
+	result.Reason = reason
+	return result
+}
+
+// keyID identifies a public key by its first 8 bytes
+func keyID(key ed25519.PublicKey) string {
+	return hex.EncodeToString(key[:8])
+}
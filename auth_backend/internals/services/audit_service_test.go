@@ -0,0 +1,265 @@
+package services_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeAuditRepo is an in-memory AuditRepo
+type fakeAuditRepo struct {
+	mu          sync.Mutex
+	events      []models.AuditEvent
+	checkpoints []models.AuditCheckpoint
+}
+
+func (r *fakeAuditRepo) AppendEvent(ctx context.Context, event *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = uint(len(r.events) + 1)
+	event.PrevHash = ""
+	if len(r.events) > 0 {
+		event.PrevHash = r.events[len(r.events)-1].Hash
+	}
+	event.Hash = event.ChainHash()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *fakeAuditRepo) ListEventsAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []models.AuditEvent
+	for _, event := range r.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeAuditRepo) LastEvent(ctx context.Context) (*models.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	event := r.events[len(r.events)-1]
+	return &event, nil
+}
+
+func (r *fakeAuditRepo) AppendCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	checkpoint.ID = uint(len(r.checkpoints) + 1)
+	r.checkpoints = append(r.checkpoints, *checkpoint)
+	return nil
+}
+
+func (r *fakeAuditRepo) LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.checkpoints) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	checkpoint := r.checkpoints[len(r.checkpoints)-1]
+	return &checkpoint, nil
+}
+
+func (r *fakeAuditRepo) ListCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.AuditCheckpoint(nil), r.checkpoints...), nil
+}
+
+func (r *fakeAuditRepo) ListEvents(ctx context.Context, filter repositories.AuditFilter) ([]models.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []models.AuditEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if filter.EventType != "" && event.EventType != filter.EventType {
+			continue
+		}
+		if filter.ActorID != 0 && (event.ActorID == nil || *event.ActorID != filter.ActorID) {
+			continue
+		}
+		if filter.TargetID != "" && event.TargetID != filter.TargetID {
+			continue
+		}
+		matched = append(matched, event)
+	}
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return nil, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// types returns the recorded event types, oldest first
+func (r *fakeAuditRepo) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []string
+	for _, event := range r.events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+// TestAuditQueryFiltersAndPages checks filtering, newest-first order and paging
+func TestAuditQueryFiltersAndPages(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAuditRepo{}
+	audit := services.NewAuditService(repo, config.AuditConfig{})
+	client := dto.ClientInfo{IP: "198.51.100.4", UserAgent: "curl/8.0"}
+	for i := 0; i < 5; i++ {
+		audit.Record(ctx, services.AuditEntry{Type: services.EventLoginSucceeded, Actor: dto.Actor{ID: 7, Email: "a@example.com", ClientInfo: client}})
+	}
+	audit.Record(ctx, services.AuditEntry{Type: services.EventLoginFailed, Actor: dto.Actor{ClientInfo: client}, Metadata: map[string]any{"email": "x@example.com"}})
+
+	page, err := audit.QueryEventsService(ctx, dto.AuditQuery{EventType: services.EventLoginSucceeded, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), page.Total)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, uint(4), page.Events[0].ID)
+	assert.Equal(t, uint(7), *page.Events[0].ActorID)
+	assert.Equal(t, "198.51.100.4", page.Events[0].IP)
+	assert.WithinDuration(t, time.Now(), page.Events[0].CreatedAt, time.Minute)
+
+	// Anonymous events have no actor, default page size applies
+	page, err = audit.QueryEventsService(ctx, dto.AuditQuery{EventType: services.EventLoginFailed})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Nil(t, page.Events[0].ActorID)
+	assert.Equal(t, "x@example.com", page.Events[0].Metadata["email"])
+	assert.Equal(t, 50, page.Limit)
+}
+
+// newChainedAudit returns an audit service with a signing key and five recorded events
+func newChainedAudit(t *testing.T) (services.AuditService, *fakeAuditRepo, config.AuditConfig) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	cfg := config.AuditConfig{SigningKey: key, PublicKey: key.Public().(ed25519.PublicKey)}
+
+	repo := &fakeAuditRepo{}
+	audit := services.NewAuditService(repo, cfg)
+	for i := 0; i < 5; i++ {
+		audit.Record(context.Background(), services.AuditEntry{
+			Type:     services.EventLoginSucceeded,
+			Actor:    dto.Actor{ID: uint(i + 1), Email: "user@example.com"},
+			Metadata: map[string]any{"attempt": i},
+		})
+	}
+	return audit, repo, cfg
+}
+
+// TestAuditChainVerification checks that edits, removals and truncation are each reported
+func TestAuditChainVerification(t *testing.T) {
+	ctx := context.Background()
+	audit, repo, _ := newChainedAudit(t)
+	checkpoint, err := audit.CheckpointService(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, uint(5), checkpoint.EventID)
+
+	// Nothing new, no new checkpoint
+	again, err := audit.CheckpointService(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	result, err := audit.VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, 5, result.EventsChecked)
+	assert.Equal(t, 1, result.CheckpointsChecked)
+
+	// Edited event
+	original := repo.events[2]
+	repo.events[2].ActorEmail = "someone-else@example.com"
+	result, err = audit.VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.FirstBrokenEventID)
+	repo.events[2] = original
+
+	// Removed event: the next one no longer links
+	repo.events = append(repo.events[:1:1], repo.events[2:]...)
+	result, err = audit.VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.FirstBrokenEventID)
+
+	// Truncated tail: only the checkpoint notices
+	audit, repo, _ = newChainedAudit(t)
+	_, err = audit.CheckpointService(ctx)
+	require.NoError(t, err)
+	repo.events = repo.events[:3]
+	result, err = audit.VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(1), result.FirstBrokenCheckpoint)
+}
+
+// TestAuditCheckpointSignature checks that a checkpoint signed with another key is rejected
+func TestAuditCheckpointSignature(t *testing.T) {
+	ctx := context.Background()
+	audit, repo, _ := newChainedAudit(t)
+	_, err := audit.CheckpointService(ctx)
+	require.NoError(t, err)
+
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	result, err := services.NewAuditService(repo, config.AuditConfig{PublicKey: otherPublic}).VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "signature is invalid", result.Reason)
+
+	// Without any key the chain is still checked, the checkpoint is reported unverified
+	result, err = services.NewAuditService(repo, config.AuditConfig{}).VerifyChainService(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 1, result.CheckpointsUnverified)
+
+	_, err = services.NewAuditService(repo, config.AuditConfig{}).CheckpointService(ctx)
+	assert.ErrorIs(t, err, services.ErrAuditSigningDisabled)
+}
+
+// TestAuditExportJSONLines checks one event per line, in chain order, with the hashes
+func TestAuditExportJSONLines(t *testing.T) {
+	ctx := context.Background()
+	audit, repo, _ := newChainedAudit(t)
+	var buf bytes.Buffer
+	require.NoError(t, audit.ExportService(ctx, &buf))
+
+	scanner := bufio.NewScanner(&buf)
+	prevHash := ""
+	lines := 0
+	for scanner.Scan() {
+		var event dto.AuditEventResponse
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.Equal(t, prevHash, event.PrevHash)
+		assert.Equal(t, repo.events[lines].Hash, event.Hash)
+		prevHash = event.Hash
+		lines++
+	}
+	assert.Equal(t, 5, lines)
+}
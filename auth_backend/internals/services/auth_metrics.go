@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/devesh121/userAuth/monitoring/metrics"
+)
+
+// unknownAuthMethod labels attempts no strategy could attribute, e.g. an email nobody has
+const unknownAuthMethod = "unknown"
+
+// countRegistration counts a new account created by an auth method
+func countRegistration(authMethod string) {
+	metrics.RegistrationsTotal.WithLabelValues(authMethod).Inc()
+}
+
+// countLogin counts a login attempt with the error it ended with, nil for a success
+func countLogin(authMethod string, err error) {
+	if authMethod == "" {
+		authMethod = unknownAuthMethod
+	}
+	metrics.LoginsTotal.WithLabelValues(authMethod, loginOutcome(err)).Inc()
+}
+
+// loginOutcome is the outcome label of a login attempt. There is no locked or mfa_required
+// outcome: no strategy locks accounts after failed attempts (they are only recorded in the login
+// history) or asks for a second factor. Neither is there a refresh counter, as the JWTs can't be
+// refreshed: a client logs in again and is counted here.
+func loginOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrInvalidCredentials):
+		return "bad_password"
+	case errors.Is(err, ErrUserNotFound):
+		return "unknown_user"
+	case errors.Is(err, ErrSessionLimitReached):
+		return "session_limit"
+	default:
+		return "failed"
+	}
+}
+
+// countRevocation counts a session or token revoked before its expiry
+func countRevocation(authMethod, reason string) {
+	if authMethod == "" {
+		authMethod = unknownAuthMethod
+	}
+	metrics.TokenRevocationsTotal.WithLabelValues(authMethod, reason).Inc()
+}
@@ -0,0 +1,77 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthMetrics checks the registration, login and revocation counters and the active users count
+func TestAuthMetrics(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), newTestEmailService(repo, audit), newTestAPIKeyService(repo))
+
+	// delta returns how much a counter moved since the call
+	delta := func(counter prometheus.Counter) func() float64 {
+		before := testutil.ToFloat64(counter)
+		return func() float64 { return testutil.ToFloat64(counter) - before }
+	}
+	registered := delta(metrics.RegistrationsTotal.WithLabelValues("local"))
+	succeeded := delta(metrics.LoginsTotal.WithLabelValues("local", "success"))
+	badPassword := delta(metrics.LoginsTotal.WithLabelValues("local", "bad_password"))
+	unknownUser := delta(metrics.LoginsTotal.WithLabelValues("unknown", "unknown_user"))
+	revoked := delta(metrics.TokenRevocationsTotal.WithLabelValues("local", "user"))
+
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		_, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "User", Email: email, Password: "secret-pass", Age: 30}, dto.ClientInfo{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2.0, registered())
+
+	var tokens []string
+	for _, email := range []string{"ann@example.com", "ann@example.com", "bob@example.com"} {
+		_, token, err := userService.LoginUserService(ctx, dto.LoginRequest{Email: email, Password: "secret-pass"}, dto.ClientInfo{})
+		require.NoError(t, err)
+		tokens = append(tokens, token)
+	}
+	_, _, err := userService.LoginUserService(ctx, dto.LoginRequest{Email: "ann@example.com", Password: "wrong-pass"}, dto.ClientInfo{})
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	_, _, err = userService.LoginUserService(ctx, dto.LoginRequest{Email: "nobody@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	assert.Error(t, err)
+	assert.Equal(t, 3.0, succeeded())
+	assert.Equal(t, 1.0, badPassword())
+	assert.Equal(t, 1.0, unknownUser())
+
+	// Users with several sessions count once
+	active, err := sessions.CountActiveUsersService(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, active)
+
+	// Bob signs out, Ann signs out everywhere
+	bob, err := utils.ValidateJWT(tokens[2])
+	require.NoError(t, err)
+	require.NoError(t, sessions.RevokeSessionService(ctx, bob.UserID, bob.SessionID))
+	require.NoError(t, sessions.RevokeSessionService(ctx, bob.UserID, bob.SessionID), "signing out twice counts once")
+	ann, err := utils.ValidateJWT(tokens[0])
+	require.NoError(t, err)
+	_, err = sessions.RevokeOtherSessionsService(ctx, ann.UserID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, revoked())
+
+	active, err = sessions.CountActiveUsersService(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, active)
+}
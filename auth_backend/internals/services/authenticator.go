@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUserNotFound is returned by an Authenticator that doesn't know the login
+	ErrUserNotFound = NewError(ErrUnauthorized, "unknown_user", "no such user found")
+	// ErrInvalidCredentials is returned by an Authenticator when the password is wrong
+	ErrInvalidCredentials = NewError(ErrUnauthorized, "invalid_credentials", "invalid credentials password or email")
+)
+
+// Authenticator is one login strategy behind LoginUserService (local bcrypt, LDAP, ...)
+type Authenticator interface {
+	Name() string
+	// Authenticate checks the credentials and returns the local user for them
+	Authenticate(ctx context.Context, login, password string) (*models.User, error)
+}
+
+// localAuthenticator checks the bcrypt password hash stored in the users table
+type localAuthenticator struct {
+	userRepo repositories.UserRepo
+}
+
+// NewLocalAuthenticator returns the default email + bcrypt password strategy
+func NewLocalAuthenticator(repo repositories.UserRepo) Authenticator {
+	return &localAuthenticator{userRepo: repo}
+}
+
+func (a *localAuthenticator) Name() string { return "local" }
+
+// Authenticate finds the user by email and compares the password hash
+func (a *localAuthenticator) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	user, err := a.userRepo.GetUserByEmail(ctx, login)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/mailer"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+	"gorm.io/gorm"
+)
+
+// Validity of the links sent by email
+const (
+	VerificationTokenTTL = 48 * time.Hour     // verify or confirm an email
+	RevertTokenTTL       = 7 * 24 * time.Hour // undo an email change, from the old address
+)
+
+// ErrEmailTokenInvalid is returned for a verification link that is forged, expired, or for an
+// email the user doesn't have anymore
+var ErrEmailTokenInvalid = NewError(ErrValidation, "email_token_invalid", "the link is invalid or has expired, request a new one")
+
+// EmailVerificationService proves that users own their email, by mailing them a link. A changed
+// email stays pending until confirmed from the new address, and the old address can revert it.
+type EmailVerificationService interface {
+	SendVerification(user *models.User)
+	SendEmailChange(user *models.User)
+	VerifyEmailService(ctx context.Context, token string) (*dto.UserResponse, error)
+	ConfirmEmailChangeService(ctx context.Context, token string) (*dto.UserResponse, error)
+	RevertEmailChangeService(ctx context.Context, token string) (*dto.UserResponse, error)
+}
+
+// emailVerificationServiceImpl struct implements the EmailVerificationService interface
+type emailVerificationServiceImpl struct {
+	userRepo  repositories.UserRepo
+	sessions  SessionService // signed out everywhere when a change is reverted
+	audit     AuditService
+	mailer    mailer.Mailer
+	publicURL string // prefix of the links, see config.GetPublicURL
+}
+
+// NewEmailVerificationService returns implementation of EmailVerificationService
+func NewEmailVerificationService(repo repositories.UserRepo, sessions SessionService, audit AuditService, mail mailer.Mailer, publicURL string) EmailVerificationService {
+	return &emailVerificationServiceImpl{userRepo: repo, sessions: sessions, audit: audit, mailer: mail, publicURL: publicURL}
+}
+
+// SendVerification mails the user a link confirming their current email, in the background
+func (s *emailVerificationServiceImpl) SendVerification(user *models.User) {
+	link, err := s.link("verify-email", utils.PurposeVerifyEmail, user.ID, user.Email, "", VerificationTokenTTL)
+	if err != nil {
+		log.Printf("failed to create verification link for user %d: %v", user.ID, err)
+		return
+	}
+
+	s.send(user.ID, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+please confirm that this is your email address by opening this link within %d hours:
+
+%s
+
+If you didn't sign up, you can ignore this message.
+`, user.Name, int(VerificationTokenTTL.Hours()), link),
+	})
+}
+
+// SendEmailChange mails a confirmation link to the pending email of the user, and a notice with
+// a link undoing the change to the current one, in the background
+func (s *emailVerificationServiceImpl) SendEmailChange(user *models.User) {
+	confirm, err := s.link("confirm-email", utils.PurposeConfirmEmail, user.ID, user.PendingEmail, "", VerificationTokenTTL)
+	if err != nil {
+		log.Printf("failed to create email change links for user %d: %v", user.ID, err)
+		return
+	}
+	revert, err := s.link("revert-email", utils.PurposeRevertEmail, user.ID, user.Email, user.PendingEmail, RevertTokenTTL)
+	if err != nil {
+		log.Printf("failed to create email change links for user %d: %v", user.ID, err)
+		return
+	}
+
+	s.send(user.ID, mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+please confirm that you want to use this email address for your account by opening this link
+within %d hours:
+
+%s
+
+Until then your account keeps using %s. If you didn't ask for this, you can ignore this message.
+`, user.Name, int(VerificationTokenTTL.Hours()), confirm, user.Email),
+	})
+	s.send(user.ID, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf(`Hi %s,
+
+someone asked to change the email address of your account to %s. The change is made once it is
+confirmed from that address.
+
+If this wasn't you, open this link within %d days to keep this address and sign out everywhere,
+then change your password:
+
+%s
+`, user.Name, user.PendingEmail, int(RevertTokenTTL.Hours()/24), revert),
+	})
+}
+
+// link returns the URL of route under /api/v1/users carrying a new action token
+func (s *emailVerificationServiceImpl) link(route, purpose string, userID uint, email, newEmail string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateActionToken(purpose, userID, email, newEmail, ttl)
+	if err != nil {
+		return "", err
+	}
+	return s.publicURL + "/api/v1/users/" + route + "?token=" + url.QueryEscape(token), nil
+}
+
+// send mails msg in the background
+func (s *emailVerificationServiceImpl) send(userID uint, msg mailer.Message) {
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("failed to send %q email to user %d: %v", msg.Subject, userID, err)
+		}
+	}()
+}
+
+// VerifyEmailService marks the email of a verification link as verified. Opening the link again
+// is harmless; a link for an email the user has changed since is refused.
+func (s *emailVerificationServiceImpl) VerifyEmailService(ctx context.Context, token string) (*dto.UserResponse, error) {
+	// Step 1: The link must be ours, unexpired, and for the email the user has now
+	claims, err := utils.ValidateActionToken(token, utils.PurposeVerifyEmail)
+	if err != nil {
+		return nil, ErrEmailTokenInvalid
+	}
+	user, err := s.tokenUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != claims.Email {
+		return nil, ErrEmailTokenInvalid
+	}
+
+	// Step 2: Mark it verified
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if user, err = s.userRepo.UpdateUser(ctx, user); err != nil {
+			return nil, err
+		}
+		s.audit.Record(ctx, AuditEntry{
+			Type:       EventEmailVerified,
+			Actor:      dto.Actor{ID: user.ID, Email: user.Email},
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	}
+
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// ConfirmEmailChangeService switches the user to the pending email of a confirmation link, which
+// is verified by opening it. A link for an email that isn't pending anymore is refused, unless it
+// is already the current one.
+func (s *emailVerificationServiceImpl) ConfirmEmailChangeService(ctx context.Context, token string) (*dto.UserResponse, error) {
+	// Step 1: The link must be ours, unexpired, and for the email waiting for confirmation
+	claims, err := utils.ValidateActionToken(token, utils.PurposeConfirmEmail)
+	if err != nil {
+		return nil, ErrEmailTokenInvalid
+	}
+	user, err := s.tokenUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == claims.Email && user.PendingEmail == "" {
+		response := newUserResponse(user) // opened again
+		return &response, nil
+	}
+	if user.PendingEmail != claims.Email {
+		return nil, ErrEmailTokenInvalid
+	}
+
+	// Step 2: Switch to it, unless someone took it in the meantime
+	previous := user.Email
+	now := time.Now()
+	user.Email, user.PendingEmail, user.EmailVerifiedAt = claims.Email, "", &now
+	if user, err = s.saveEmail(ctx, user); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventEmailChanged,
+		Actor:      dto.Actor{ID: user.ID, Email: user.Email},
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		Metadata:   map[string]any{"from": previous, "to": user.Email},
+	})
+
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// RevertEmailChangeService undoes the email change of a revert link, pending or already
+// confirmed, and signs the user out of every session: whoever asked for it may have their
+// password. The link only applies while the account is at either end of that change.
+func (s *emailVerificationServiceImpl) RevertEmailChangeService(ctx context.Context, token string) (*dto.UserResponse, error) {
+	// Step 1: The link must be ours, unexpired, and for the change the user is in
+	claims, err := utils.ValidateActionToken(token, utils.PurposeRevertEmail)
+	if err != nil {
+		return nil, ErrEmailTokenInvalid
+	}
+	user, err := s.tokenUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	pending := user.Email == claims.Email && user.PendingEmail == claims.NewEmail
+	confirmed := user.Email == claims.NewEmail
+	if user.Email == claims.Email && user.PendingEmail == "" {
+		response := newUserResponse(user) // opened again, or the change was dropped
+		return &response, nil
+	}
+	if !pending && !confirmed {
+		return nil, ErrEmailTokenInvalid
+	}
+
+	// Step 2: Go back to the old email, which the link just proved to be owned
+	now := time.Now()
+	user.Email, user.PendingEmail, user.EmailVerifiedAt = claims.Email, "", &now
+	if user, err = s.saveEmail(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Sign out everywhere
+	if _, err := s.sessions.RevokeOtherSessionsService(ctx, user.ID, ""); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventEmailChangeReverted,
+		Actor:      dto.Actor{ID: user.ID, Email: user.Email},
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		Metadata:   map[string]any{"email": claims.NewEmail, "confirmed": confirmed},
+	})
+
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// tokenUser returns the user of a valid action token
+func (s *emailVerificationServiceImpl) tokenUser(ctx context.Context, claims *utils.ActionClaims) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEmailTokenInvalid.Wrap(err)
+	}
+	return user, err
+}
+
+// saveEmail saves a user whose email changed, a taken email being a conflict
+func (s *emailVerificationServiceImpl) saveEmail(ctx context.Context, user *models.User) (*models.User, error) {
+	saved, err := s.userRepo.UpdateUser(ctx, user)
+	switch {
+	case errors.Is(err, repositories.ErrDuplicateEmail):
+		return nil, ErrEmailTaken.Wrap(err)
+	case errors.Is(err, repositories.ErrStaleUser):
+		return nil, ErrVersionMismatch.Wrap(err) // updated by someone else in between
+	}
+	return saved, err
+}
@@ -0,0 +1,161 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEmailChangeNeedsConfirmation checks that a patch changes only its fields and that a new
+// email is used only once the link mailed to it is opened, while links for the old one stop working
+func TestEmailChangeNeedsConfirmation(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	mail := &fakeMailer{}
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	emails := services.NewEmailVerificationService(repo, sessions, audit, mail, "https://auth.example.com")
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), emails, newTestAPIKeyService(repo))
+	linkToken := mailedLinks(t, mail)
+
+	ann, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass", Age: 30}, dto.ClientInfo{})
+	require.NoError(t, err)
+	assert.False(t, ann.EmailVerified)
+	firstToken := linkToken("ann@example.com", "verify-email")
+	verified, err := emails.VerifyEmailService(ctx, firstToken)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+
+	// Only the fields in the patch change, and zero values can be set
+	age := 0
+	patched, err := userService.PatchUserService(ctx, dto.UserPatch{Age: &age}, ann.ID, verified.Version, dto.Actor{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, patched.Age)
+	assert.Equal(t, "Ann", patched.Name)
+	assert.True(t, patched.EmailVerified)
+
+	// An email used by someone else is refused right away
+	_, err = userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Bob", Email: "bob@example.com", Password: "secret-pass", Age: 40}, dto.ClientInfo{})
+	require.NoError(t, err)
+	taken := "bob@example.com"
+	_, err = userService.PatchUserService(ctx, dto.UserPatch{Email: &taken}, ann.ID, 0, dto.Actor{})
+	assert.ErrorIs(t, err, services.ErrEmailTaken)
+
+	// A new email stays pending, the account keeps using the verified one
+	email := "ann.b@example.com"
+	patched, err = userService.PatchUserService(ctx, dto.UserPatch{Email: &email}, ann.ID, patched.Version, dto.Actor{})
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", patched.Email)
+	assert.Equal(t, email, patched.PendingEmail)
+	assert.True(t, patched.EmailVerified)
+	confirmToken := linkToken(email, "confirm-email")
+	linkToken("ann@example.com", "revert-email")
+
+	// Someone taking the pending email first makes the confirmation a conflict
+	carl, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Carl", Email: email, Password: "secret-pass", Age: 50}, dto.ClientInfo{})
+	require.NoError(t, err)
+	_, err = emails.ConfirmEmailChangeService(ctx, confirmToken)
+	assert.ErrorIs(t, err, services.ErrEmailTaken)
+	require.NoError(t, userService.DeleteUserService(ctx, carl.ID, dto.Actor{}))
+
+	confirmed, err := emails.ConfirmEmailChangeService(ctx, confirmToken)
+	require.NoError(t, err)
+	assert.Equal(t, email, confirmed.Email)
+	assert.Empty(t, confirmed.PendingEmail)
+	assert.True(t, confirmed.EmailVerified)
+	_, err = emails.ConfirmEmailChangeService(ctx, confirmToken)
+	assert.NoError(t, err, "opening the link again is harmless")
+
+	_, err = emails.VerifyEmailService(ctx, firstToken)
+	assert.ErrorIs(t, err, services.ErrEmailTokenInvalid)
+	_, err = emails.ConfirmEmailChangeService(ctx, "not-a-token")
+	assert.ErrorIs(t, err, services.ErrEmailTokenInvalid)
+	_, err = emails.ConfirmEmailChangeService(ctx, firstToken)
+	assert.ErrorIs(t, err, services.ErrEmailTokenInvalid, "tokens only serve their purpose")
+}
+
+// TestRevertEmailChange checks that the old address can undo an email change, pending or
+// confirmed, which signs the user out everywhere
+func TestRevertEmailChange(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	mail := &fakeMailer{}
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	emails := services.NewEmailVerificationService(repo, sessions, audit, mail, "https://auth.example.com")
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), emails, newTestAPIKeyService(repo))
+	linkToken := mailedLinks(t, mail)
+
+	ann, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass", Age: 30}, dto.ClientInfo{})
+	require.NoError(t, err)
+	user, err := repo.GetUserByID(ctx, ann.ID)
+	require.NoError(t, err)
+	startSession(t, sessions, user, "laptop")
+
+	// Pending: the change is dropped
+	email := "mallory@example.com"
+	_, err = userService.PatchUserService(ctx, dto.UserPatch{Email: &email}, ann.ID, 0, dto.Actor{})
+	require.NoError(t, err)
+	confirmToken := linkToken(email, "confirm-email")
+	reverted, err := emails.RevertEmailChangeService(ctx, linkToken("ann@example.com", "revert-email"))
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", reverted.Email)
+	assert.Empty(t, reverted.PendingEmail)
+	_, err = emails.ConfirmEmailChangeService(ctx, confirmToken)
+	assert.ErrorIs(t, err, services.ErrEmailTokenInvalid)
+	list, err := sessions.ListSessionsService(ctx, ann.ID, "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Confirmed: the old email comes back
+	email = "ann.b@example.com"
+	_, err = userService.PatchUserService(ctx, dto.UserPatch{Email: &email}, ann.ID, 0, dto.Actor{})
+	require.NoError(t, err)
+	_, err = emails.ConfirmEmailChangeService(ctx, linkToken(email, "confirm-email"))
+	require.NoError(t, err)
+	revertToken := linkToken("ann@example.com", "revert-email")
+	reverted, err = emails.RevertEmailChangeService(ctx, revertToken)
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", reverted.Email)
+	assert.True(t, reverted.EmailVerified)
+
+	// Once the account moved on to another change, the link is refused
+	email = "ann.c@example.com"
+	_, err = userService.PatchUserService(ctx, dto.UserPatch{Email: &email}, ann.ID, 0, dto.Actor{})
+	require.NoError(t, err)
+	_, err = emails.RevertEmailChangeService(ctx, revertToken)
+	assert.ErrorIs(t, err, services.ErrEmailTokenInvalid)
+}
+
+// mailedLinks returns a function waiting for a new mail to an address with a link to route, and
+// returning the token of that link
+func mailedLinks(t *testing.T, mail *fakeMailer) func(to, route string) string {
+	seen := map[string]bool{}
+	return func(to, route string) string {
+		prefix := "https://auth.example.com/api/v1/users/" + route + "?token="
+		var token string
+		require.Eventually(t, func() bool {
+			for _, msg := range mail.messages() {
+				_, link, found := strings.Cut(msg.Body, prefix)
+				if !found || msg.To != to || seen[link] {
+					continue
+				}
+				seen[link] = true
+				var err error
+				token, err = url.QueryUnescape(strings.Fields(link)[0])
+				return err == nil
+			}
+			return false
+		}, time.Second, 5*time.Millisecond, "no %s link mailed to %s", route, to)
+		return token
+	}
+}
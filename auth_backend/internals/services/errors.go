@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devesh121/userAuth/internals/dto"
+)
+
+// Error kinds. Every domain error belongs to one of them, check with errors.Is(err, ErrNotFound).
+// The kind decides the HTTP status (see middlewares.ErrorHandler).
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+
+	ErrPreconditionFailed   = errors.New("precondition failed")    // the resource changed since the client read it
+	ErrPreconditionRequired = errors.New("precondition required")  // the client must say which version it read
+	ErrUnsupportedMediaType = errors.New("unsupported media type") // the request body has the wrong Content-Type
+)
+
+// Error is a domain error returned by the services. Code is a stable, machine readable
+// identifier (e.g. "user_not_found"), Message is safe to show to clients, and Err is the
+// optional underlying cause, which isn't. Validation errors may list the invalid Fields.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []dto.FieldError
+	Err     error
+}
+
+// NewError returns a domain error of the given kind
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap returns a copy of e carrying cause, so errors.Is matches both e and cause
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap exposes the kind and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// Is matches copies made by Wrap against the sentinel they were made from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Errors of the user endpoints
+var (
+	ErrUserNotExist = NewError(ErrNotFound, "user_not_found", "user not found")
+	ErrEmailTaken   = NewError(ErrConflict, "email_taken", "user already exists")
+
+	ErrVersionMismatch = NewError(ErrPreconditionFailed, "version_mismatch", "the user was changed since it was read, fetch it again")
+	ErrVersionRequired = NewError(ErrPreconditionRequired, "if_match_required", "send the ETag of the user in If-Match")
+)
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/providers"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// Errors of the federated login
+var (
+	ErrUnknownProvider      = NewError(ErrNotFound, "unknown_provider", "unknown identity provider")
+	ErrFederatedLoginFailed = NewError(ErrUnauthorized, "federated_login_failed", "login with the identity provider failed")
+	ErrUnverifiedEmail      = NewError(ErrUnauthorized, "unverified_email", "identity provider did not return a verified email")
+)
+
+// FederationService handles login through upstream identity providers
+type FederationService interface {
+	BeginLoginService(providerName string) (string, *dto.FederationState, error)
+	CompleteLoginService(ctx context.Context, providerName, code string, state dto.FederationState, client dto.ClientInfo) (*dto.LoginResponse, string, error)
+}
+
+// federationServiceImpl struct implements the FederationService interface
+type federationServiceImpl struct {
+	userRepo     repositories.UserRepo
+	identityRepo repositories.IdentityRepo
+	sessions     SessionService
+	history      LoginHistoryService
+	audit        AuditService
+	providers    map[string]providers.Provider
+}
+
+// NewFederationService returns a FederationService for the given providers
+func NewFederationService(userRepo repositories.UserRepo, identityRepo repositories.IdentityRepo, sessions SessionService, history LoginHistoryService, audit AuditService, list []providers.Provider) FederationService {
+	byName := make(map[string]providers.Provider, len(list))
+	for _, p := range list {
+		byName[p.Name()] = p
+	}
+	return &federationServiceImpl{userRepo: userRepo, identityRepo: identityRepo, sessions: sessions, history: history, audit: audit, providers: byName}
+}
+
+// BeginLoginService returns the provider's authorization URL and the state to remember until the callback
+func (s *federationServiceImpl) BeginLoginService(providerName string) (string, *dto.FederationState, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", nil, ErrUnknownProvider
+	}
+
+	state, err := utils.RandomString(24)
+	if err != nil {
+		return "", nil, errors.New("failed to generate state")
+	}
+	nonce, err := utils.RandomString(24)
+	if err != nil {
+		return "", nil, errors.New("failed to generate nonce")
+	}
+
+	fs := &dto.FederationState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+	return provider.AuthCodeURL(fs.State, fs.Nonce, fs.CodeVerifier), fs, nil
+}
+
+// CompleteLoginService exchanges the code, then logs in the linked user or links / creates one by verified email
+func (s *federationServiceImpl) CompleteLoginService(ctx context.Context, providerName, code string, state dto.FederationState, client dto.ClientInfo) (_ *dto.LoginResponse, _ string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, "", ErrUnknownProvider
+	}
+	var (
+		user  *models.User
+		email string
+	)
+	defer func() {
+		countLogin(providerName, err)
+		recordLogin(ctx, s.audit, user, email, providerName, client, err)
+	}()
+
+	// Step 1: Exchange the code for the upstream identity
+	identity, err := provider.Exchange(ctx, code, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		return nil, "", NewError(ErrUnauthorized, ErrFederatedLoginFailed.Code, fmt.Sprintf("login with %s failed", providerName)).Wrap(err)
+	}
+	email = identity.Email
+
+	// Step 2: Resolve the local user
+	if user, err = s.resolveUser(ctx, identity); err != nil {
+		return nil, "", err
+	}
+
+	// Step 3: Start a session and issue our own token, same as a password login
+	token, err := s.sessions.StartSessionService(ctx, user, client, providerName)
+	s.history.RecordLoginService(ctx, LoginAttemptInput{User: user, AuthMethod: providerName, Client: client, Err: err})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &dto.LoginResponse{
+		ID:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+		Age:   user.Age,
+		Role:  user.Role,
+	}, token, nil
+}
+
+// resolveUser finds the user for an upstream identity, linking or creating one if needed
+func (s *federationServiceImpl) resolveUser(ctx context.Context, identity *providers.Identity) (*models.User, error) {
+	// Already linked: log in as the linked user
+	link, err := s.identityRepo.GetIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetUserByID(ctx, link.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFederatedLoginFailed.Wrap(err) // the linked user was deleted
+		}
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Not linked yet: only a verified email may be used to find or create the account
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrUnverifiedEmail
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.createFederatedUser(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.identityRepo.CreateIdentity(ctx, &models.LinkedIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return nil, errors.New("failed to link identity")
+	}
+	return user, nil
+}
+
+// createFederatedUser creates a local account that can only be used through the provider
+func (s *federationServiceImpl) createFederatedUser(ctx context.Context, identity *providers.Identity) (*models.User, error) {
+	// Random password nobody knows, so password login is not possible for this account
+	password, err := unusablePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identity.Email
+	}
+	// The provider already verified the email, no need to send our own link
+	var verifiedAt *time.Time
+	if identity.EmailVerified {
+		now := time.Now()
+		verifiedAt = &now
+	}
+
+	user, err := s.userRepo.CreateUser(ctx, &models.User{
+		Name:            name,
+		Email:           identity.Email,
+		Password:        password,
+		Role:            "user",
+		EmailVerifiedAt: verifiedAt,
+	})
+	if err != nil {
+		return nil, errors.New("failed to create user")
+	}
+	countRegistration(identity.Provider)
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventUserRegistered,
+		Actor:      dto.Actor{ID: user.ID, Email: user.Email},
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		Metadata:   map[string]any{"role": user.Role, "auth_source": identity.Provider},
+	})
+	return user, nil
+}
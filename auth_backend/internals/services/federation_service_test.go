@@ -0,0 +1,86 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/providers"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeIdentityRepo is an in-memory IdentityRepo
+type fakeIdentityRepo struct {
+	mu         sync.Mutex
+	identities []models.LinkedIdentity
+}
+
+func (r *fakeIdentityRepo) CreateIdentity(ctx context.Context, identity *models.LinkedIdentity) (*models.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities = append(r.identities, *identity)
+	return identity, nil
+}
+
+func (r *fakeIdentityRepo) GetIdentity(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeProvider answers every code with the same identity
+type fakeProvider struct {
+	identity providers.Identity
+}
+
+func (p *fakeProvider) Name() string { return p.identity.Provider }
+
+func (p *fakeProvider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return "https://idp.example.com/authorize?state=" + state
+}
+
+func (p *fakeProvider) Exchange(ctx context.Context, code, nonce, codeVerifier string) (*providers.Identity, error) {
+	identity := p.identity
+	return &identity, nil
+}
+
+// TestFederatedLogin checks that a new identity creates a verified account, that the link of a
+// deleted account no longer logs in, and the audit events of both
+func TestFederatedLogin(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := &fakeAuditRepo{}
+	audit := services.NewAuditService(auditRepo, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	provider := &fakeProvider{identity: providers.Identity{Provider: "google", Subject: "sub-1", Email: "ann@example.com", EmailVerified: true, Name: "Ann"}}
+	svc := services.NewFederationService(repo, &fakeIdentityRepo{}, sessions, newTestLoginHistory(), audit, []providers.Provider{provider})
+
+	response, token, err := svc.CompleteLoginService(ctx, "google", "code", dto.FederationState{}, dto.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	user, err := repo.GetUserByID(ctx, response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", user.Name)
+	assert.NotNil(t, user.EmailVerifiedAt, "the provider verified the email")
+
+	require.NoError(t, repo.DeleteUser(ctx, user.ID))
+	_, _, err = svc.CompleteLoginService(ctx, "google", "code", dto.FederationState{}, dto.ClientInfo{})
+	assert.ErrorIs(t, err, services.ErrFederatedLoginFailed)
+
+	assert.Equal(t, []string{services.EventUserRegistered, services.EventLoginSucceeded, services.EventLoginFailed}, auditRepo.types())
+	failed := auditRepo.events[2]
+	assert.Equal(t, "ann@example.com", failed.Metadata["email"])
+	assert.Equal(t, "google", failed.Metadata["method"])
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapAuthenticator authenticates against LDAP / Active Directory with search + bind
+// and provisions the matching models.User on first login.
+type ldapAuthenticator struct {
+	cfg      config.LDAPConfig
+	userRepo repositories.UserRepo
+	audit    AuditService
+}
+
+// NewLDAPAuthenticator returns the LDAP bind strategy
+func NewLDAPAuthenticator(cfg config.LDAPConfig, repo repositories.UserRepo, audit AuditService) Authenticator {
+	return &ldapAuthenticator{cfg: cfg, userRepo: repo, audit: audit}
+}
+
+func (a *ldapAuthenticator) Name() string { return "ldap" }
+
+// Authenticate looks the user up in the directory, binds with their password and syncs the local user
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	// An empty password would be an unauthenticated bind, which most servers accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Each step is bounded, and the connection is closed as soon as the request is done, so an
+	// unreachable directory can't hold the login
+	timeout := a.cfg.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultLDAPTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect failed: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(nil); err != nil {
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+
+	// Step 1: Bind as the service account (if configured) to search for the user
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(login)),
+		[]string{a.cfg.EmailAttribute, a.cfg.NameAttribute, a.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+	entry := result.Entries[0]
+
+	// Step 2: Bind as the user to verify the password
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	email := entry.GetAttributeValue(a.cfg.EmailAttribute)
+	if email == "" {
+		return nil, errors.New("ldap entry has no email attribute")
+	}
+
+	// Step 3: Just-in-time provisioning of the local user
+	role := mapGroupsToRole(a.cfg.GroupRoles, entry.GetAttributeValues(a.cfg.GroupAttribute), a.cfg.DefaultRole)
+	return provisionUser(ctx, a.userRepo, a.audit, "ldap", email, entry.GetAttributeValue(a.cfg.NameAttribute), role)
+}
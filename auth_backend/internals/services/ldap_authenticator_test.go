@@ -0,0 +1,304 @@
+package services_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEntry is one user in the in-process LDAP directory
+type stubEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// ldapStub is a tiny in-process LDAP server supporting simple bind and search, enough for the authenticator.
+type ldapStub struct {
+	listener     net.Listener
+	serviceDN    string
+	servicePass  string
+	entries      map[string]stubEntry // keyed by the search filter, e.g. (mail=jane@example.com)
+	bindPassword map[string]string    // dn -> password
+}
+
+func newLDAPStub(t *testing.T, entries ...stubEntry) *ldapStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &ldapStub{
+		listener:     ln,
+		serviceDN:    "cn=service,dc=example,dc=com",
+		servicePass:  "service-secret",
+		entries:      map[string]stubEntry{},
+		bindPassword: map[string]string{},
+	}
+	s.bindPassword[s.serviceDN] = s.servicePass
+	for _, e := range entries {
+		s.entries["(mail="+e.attrs["mail"][0]+")"] = e
+		s.bindPassword[e.dn] = e.password
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *ldapStub) url() string { return "ldap://" + s.listener.Addr().String() }
+
+// serve answers bind, search and unbind requests on one connection
+func (s *ldapStub) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		msgID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := int64(ldap.LDAPResultSuccess)
+			if expected, ok := s.bindPassword[dn]; !ok || expected != password {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			conn.Write(s.response(msgID, s.result(ldap.ApplicationBindResponse, code)).Bytes())
+
+		case ldap.ApplicationSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			if entry, ok := s.entries[filter]; ok {
+				conn.Write(s.response(msgID, s.searchEntry(entry)).Bytes())
+			}
+			conn.Write(s.response(msgID, s.result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)).Bytes())
+
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *ldapStub) response(msgID int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "MessageID"))
+	p.AppendChild(op)
+	return p
+}
+
+func (s *ldapStub) result(tag ber.Tag, code int64) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return p
+}
+
+func (s *ldapStub) searchEntry(entry stubEntry) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	p.AppendChild(attrs)
+	return p
+}
+
+// allUsers lists the users of a repository
+func allUsers(t *testing.T, repo repositories.UserRepo) []models.User {
+	users, err := repo.GetAllUsers(context.Background())
+	require.NoError(t, err)
+	return users
+}
+
+func testLDAPConfig(url string) config.LDAPConfig {
+	return config.LDAPConfig{
+		URL:            url,
+		BindDN:         "cn=service,dc=example,dc=com",
+		BindPassword:   "service-secret",
+		BaseDN:         "ou=people,dc=example,dc=com",
+		UserFilter:     "(mail=%s)",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+		GroupRoles: []config.GroupRole{
+			{Group: "cn=admins,ou=groups,dc=example,dc=com", Role: "admin"},
+		},
+		DefaultRole: "user",
+	}
+}
+
+var janeEntry = stubEntry{
+	dn:       "uid=jane,ou=people,dc=example,dc=com",
+	password: "directory-pass",
+	attrs: map[string][]string{
+		"mail":     {"jane@example.com"},
+		"cn":       {"Jane Doe"},
+		"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+	},
+}
+
+// TestLDAPAuthenticateProvisionsUser checks search + bind and just-in-time provisioning with group mapping
+func TestLDAPAuthenticateProvisionsUser(t *testing.T) {
+	ctx := context.Background()
+	stub := newLDAPStub(t, janeEntry)
+	repo := repositories.NewMemoryUserRepo()
+	auth := services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repo, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+
+	user, err := auth.Authenticate(ctx, "jane@example.com", "directory-pass")
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.Equal(t, "Jane Doe", user.Name)
+	assert.Equal(t, "admin", user.Role)
+	assert.Equal(t, "ldap", user.AuthSource)
+
+	// Second login reuses the provisioned row
+	again, err := auth.Authenticate(ctx, "jane@example.com", "directory-pass")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+	assert.Len(t, allUsers(t, repo), 1)
+}
+
+// TestLDAPProvisioningSyncsOnlyItsUsers checks that a local account with the directory email isn't
+// taken over, and that the role of a provisioned user follows the groups and is audited
+func TestLDAPProvisioningSyncsOnlyItsUsers(t *testing.T) {
+	ctx := context.Background()
+	stub := newLDAPStub(t, janeEntry)
+
+	// Jane's email already has a local account: the directory can't log into it or change its role
+	repo := repositories.NewMemoryUserRepo()
+	local, err := repo.CreateUser(ctx, &models.User{Name: "Jane", Email: "jane@example.com", Password: "hash", Role: "user"})
+	require.NoError(t, err)
+	auth := services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repo, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	_, err = auth.Authenticate(ctx, "jane@example.com", "directory-pass")
+	assert.ErrorIs(t, err, services.ErrAccountNotLinked)
+	unchanged, err := repo.GetUserByID(ctx, local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "user", unchanged.Role)
+
+	// A user the directory provisioned gets the role of its groups
+	repo = repositories.NewMemoryUserRepo()
+	provisioned, err := repo.CreateUser(ctx, &models.User{Name: "Jane Doe", Email: "jane@example.com", Password: "hash", Role: "user", AuthSource: "ldap"})
+	require.NoError(t, err)
+	auditRepo := &fakeAuditRepo{}
+	auth = services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repo, services.NewAuditService(auditRepo, config.AuditConfig{}))
+	user, err := auth.Authenticate(ctx, "jane@example.com", "directory-pass")
+	require.NoError(t, err)
+	assert.Equal(t, provisioned.ID, user.ID)
+	assert.Equal(t, "admin", user.Role)
+	require.Len(t, auditRepo.events, 1)
+	assert.Equal(t, services.EventRoleChanged, auditRepo.events[0].EventType)
+	assert.Equal(t, "user", auditRepo.events[0].Metadata["from"])
+	assert.Equal(t, "admin", auditRepo.events[0].Metadata["to"])
+}
+
+// TestLDAPAuthenticateWrongPassword checks that a failed user bind is reported as invalid credentials
+func TestLDAPAuthenticateWrongPassword(t *testing.T) {
+	ctx := context.Background()
+	stub := newLDAPStub(t, janeEntry)
+	auth := services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repositories.NewMemoryUserRepo(), nil)
+
+	_, err := auth.Authenticate(ctx, "jane@example.com", "wrong")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+
+	_, err = auth.Authenticate(ctx, "jane@example.com", "")
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+}
+
+// TestLDAPAuthenticateUnknownUser checks that a search without results is reported as unknown user
+func TestLDAPAuthenticateUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	stub := newLDAPStub(t, janeEntry)
+	auth := services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repositories.NewMemoryUserRepo(), nil)
+
+	_, err := auth.Authenticate(ctx, "nobody@example.com", "whatever")
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+}
+
+// TestLDAPAuthenticateUnresponsiveDirectory checks that a directory accepting connections but never
+// answering holds a login no longer than the timeout or the request
+func TestLDAPAuthenticateUnresponsiveDirectory(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() }) // held open, never answered
+		}
+	}()
+	cfg := testLDAPConfig("ldap://" + ln.Addr().String())
+
+	// Bounded by the configured timeout
+	cfg.Timeout = 100 * time.Millisecond
+	auth := services.NewLDAPAuthenticator(cfg, repositories.NewMemoryUserRepo(), nil)
+	start := time.Now()
+	_, err = auth.Authenticate(context.Background(), "jane@example.com", "directory-pass")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// Bounded by the request, even with a long timeout
+	cfg.Timeout = time.Minute
+	auth = services.NewLDAPAuthenticator(cfg, repositories.NewMemoryUserRepo(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = auth.Authenticate(ctx, "jane@example.com", "directory-pass")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+// TestLoginFallsBackToLDAP checks that LoginUserService tries local first and then LDAP
+func TestLoginFallsBackToLDAP(t *testing.T) {
+	ctx := context.Background()
+	stub := newLDAPStub(t, janeEntry)
+	repo := repositories.NewMemoryUserRepo()
+	userService := services.NewUserService(repo,
+		services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})),
+		services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}),
+		newTestLoginHistory(),
+		newTestEmailService(repo, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})),
+		newTestAPIKeyService(repo),
+		services.NewLocalAuthenticator(repo),
+		services.NewLDAPAuthenticator(testLDAPConfig(stub.url()), repo, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})),
+	)
+
+	resp, token, err := userService.LoginUserService(ctx, dto.LoginRequest{Email: "jane@example.com", Password: "directory-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", resp.Role)
+	assert.NotEmpty(t, token)
+
+	_, _, err = userService.LoginUserService(ctx, dto.LoginRequest{Email: "jane@example.com", Password: "wrong"}, dto.ClientInfo{})
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/geoip"
+	"github.com/devesh121/userAuth/internals/mailer"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+)
+
+// defaultLoginHistoryPageSize is used when the query has no limit
+const defaultLoginHistoryPageSize = 50
+
+// versionPattern matches version numbers in user agents ("120.0.6099.71", "10_15_7")
+var versionPattern = regexp.MustCompile(`\d+([._]\d+)*`)
+
+// LoginAttemptInput describes a finished login attempt
+type LoginAttemptInput struct {
+	User       *models.User // account the attempt was for
+	AuthMethod string
+	Client     dto.ClientInfo
+	Err        error // nil for a successful login
+}
+
+// LoginHistoryService records login attempts and warns users about logins from new devices or networks
+type LoginHistoryService interface {
+	RecordLoginService(ctx context.Context, attempt LoginAttemptInput)
+	ListLoginsService(ctx context.Context, userID uint, query dto.PageQuery) (*dto.LoginHistoryPage, error)
+}
+
+// loginHistoryServiceImpl struct implements the LoginHistoryService interface
+type loginHistoryServiceImpl struct {
+	historyRepo repositories.LoginHistoryRepo
+	locator     geoip.Locator
+	mailer      mailer.Mailer
+}
+
+// NewLoginHistoryService returns implementation of LoginHistoryService
+func NewLoginHistoryService(repo repositories.LoginHistoryRepo, locator geoip.Locator, mail mailer.Mailer) LoginHistoryService {
+	return &loginHistoryServiceImpl{historyRepo: repo, locator: locator, mailer: mail}
+}
+
+// RecordLoginService stores the attempt and, for a successful login from a device or network
+// the user hasn't used before, emails an alert. Failures are logged and never fail the login.
+func (s *loginHistoryServiceImpl) RecordLoginService(ctx context.Context, in LoginAttemptInput) {
+	if in.User == nil {
+		return
+	}
+	// Like the audit log, the record outlives a client that disconnects
+	ctx = context.WithoutCancel(ctx)
+
+	// Step 1: Describe the client
+	attempt := &models.LoginAttempt{
+		UserID:      in.User.ID,
+		Success:     in.Err == nil,
+		AuthMethod:  in.AuthMethod,
+		IP:          in.Client.IP,
+		Network:     ipNetwork(in.Client.IP),
+		Country:     s.locator.Country(in.Client.IP),
+		UserAgent:   truncate(in.Client.UserAgent, 512),
+		Fingerprint: fingerprintUserAgent(in.Client.UserAgent),
+		CreatedAt:   time.Now(),
+	}
+	if in.Err != nil {
+		attempt.Reason = truncate(in.Err.Error(), 255)
+	}
+
+	// Step 2: Compare a successful login with the earlier ones (the very first login has nothing to compare with)
+	if attempt.Success {
+		seenBefore, err := s.historyRepo.HasSuccessfulLogin(ctx, in.User.ID)
+		if err != nil {
+			log.Printf("failed to read login history of user %d: %v", in.User.ID, err)
+		} else if seenBefore {
+			knownDevice, err1 := s.historyRepo.KnownFingerprint(ctx, in.User.ID, attempt.Fingerprint)
+			knownNetwork, err2 := s.historyRepo.KnownNetwork(ctx, in.User.ID, attempt.Network)
+			if err1 == nil && err2 == nil {
+				attempt.NewDevice = !knownDevice
+				attempt.NewNetwork = !knownNetwork
+			}
+		}
+	}
+
+	// Step 3: Store it
+	if err := s.historyRepo.CreateLoginAttempt(ctx, attempt); err != nil {
+		log.Printf("failed to record login attempt of user %d: %v", in.User.ID, err)
+	}
+
+	// Step 4: Alert in the background, SMTP can be slow
+	if attempt.NewDevice || attempt.NewNetwork {
+		msg := newLoginAlert(in.User, attempt)
+		go func() {
+			if err := s.mailer.Send(msg); err != nil {
+				log.Printf("failed to send login alert to user %d: %v", in.User.ID, err)
+			}
+		}()
+	}
+}
+
+// ListLoginsService returns one page of the user's login attempts, newest first
+func (s *loginHistoryServiceImpl) ListLoginsService(ctx context.Context, userID uint, query dto.PageQuery) (*dto.LoginHistoryPage, error) {
+	if query.Limit == 0 {
+		query.Limit = defaultLoginHistoryPageSize
+	}
+
+	attempts, total, err := s.historyRepo.ListLoginAttempts(ctx, userID, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.LoginHistoryPage{
+		Logins: make([]dto.LoginAttemptResponse, 0, len(attempts)),
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	for _, attempt := range attempts {
+		page.Logins = append(page.Logins, dto.LoginAttemptResponse{
+			ID:          attempt.ID,
+			Success:     attempt.Success,
+			Reason:      attempt.Reason,
+			AuthMethod:  attempt.AuthMethod,
+			IP:          attempt.IP,
+			Country:     attempt.Country,
+			UserAgent:   attempt.UserAgent,
+			Fingerprint: attempt.Fingerprint,
+			NewDevice:   attempt.NewDevice,
+			NewNetwork:  attempt.NewNetwork,
+			CreatedAt:   attempt.CreatedAt,
+		})
+	}
+	return page, nil
+}
+
+// newLoginAlert builds the email sent for a login from a new device or network
+func newLoginAlert(user *models.User, attempt *models.LoginAttempt) mailer.Message {
+	var what []string
+	if attempt.NewDevice {
+		what = append(what, "a new device")
+	}
+	if attempt.NewNetwork {
+		what = append(what, "a new network")
+	}
+	location := attempt.IP
+	if attempt.Country != "" {
+		location += " (" + attempt.Country + ")"
+	}
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf(`Hi %s,
+
+your account was just signed in from %s.
+
+Time:       %s
+IP address: %s
+Device:     %s
+Method:     %s
+
+If this was you, there is nothing to do. If not, sign out that session from your
+active sessions (GET /api/v1/users/me/sessions) and change your password.
+`, user.Name, strings.Join(what, " and "), attempt.CreatedAt.UTC().Format(time.RFC1123), location, attempt.UserAgent, attempt.AuthMethod),
+	}
+}
+
+// fingerprintUserAgent identifies a device by its user agent without version numbers,
+// so browser updates don't look like a new device
+func fingerprintUserAgent(userAgent string) string {
+	normalized := versionPattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(userAgent)), "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ipNetwork returns the /24 (IPv4) or /48 (IPv6) network of ip, or ip itself when it doesn't parse
+func ipNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	bits := 48
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/mailer"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeLoginHistoryRepo is an in-memory LoginHistoryRepo
+type fakeLoginHistoryRepo struct {
+	mu       sync.Mutex
+	attempts []models.LoginAttempt
+}
+
+func (r *fakeLoginHistoryRepo) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempt.ID = uint(len(r.attempts) + 1)
+	r.attempts = append(r.attempts, *attempt)
+	return nil
+}
+
+func (r *fakeLoginHistoryRepo) ListLoginAttempts(ctx context.Context, userID uint, limit, offset int) ([]models.LoginAttempt, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []models.LoginAttempt
+	for i := len(r.attempts) - 1; i >= 0; i-- {
+		if r.attempts[i].UserID == userID {
+			matched = append(matched, r.attempts[i])
+		}
+	}
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	matched = matched[offset:]
+	if limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+func (r *fakeLoginHistoryRepo) HasSuccessfulLogin(ctx context.Context, userID uint) (bool, error) {
+	return r.any(func(a models.LoginAttempt) bool { return a.UserID == userID && a.Success }), nil
+}
+
+func (r *fakeLoginHistoryRepo) KnownFingerprint(ctx context.Context, userID uint, fingerprint string) (bool, error) {
+	return r.any(func(a models.LoginAttempt) bool {
+		return a.UserID == userID && a.Success && a.Fingerprint == fingerprint
+	}), nil
+}
+
+func (r *fakeLoginHistoryRepo) KnownNetwork(ctx context.Context, userID uint, network string) (bool, error) {
+	return r.any(func(a models.LoginAttempt) bool { return a.UserID == userID && a.Success && a.Network == network }), nil
+}
+
+func (r *fakeLoginHistoryRepo) any(match func(models.LoginAttempt) bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, attempt := range r.attempts {
+		if match(attempt) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeMailer keeps sent messages in memory
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *fakeMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+// staticLocator maps IPs to countries from a table
+type staticLocator map[string]string
+
+func (l staticLocator) Country(ip string) string { return l[ip] }
+
+// newTestLoginHistory returns a login history service on in-memory fakes
+func newTestLoginHistory() services.LoginHistoryService {
+	return services.NewLoginHistoryService(&fakeLoginHistoryRepo{}, staticLocator{}, &fakeMailer{})
+}
+
+// TestLoginHistoryAlertsOnNewDeviceOrNetwork checks the history and when alerts are sent
+func TestLoginHistoryAlertsOnNewDeviceOrNetwork(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeLoginHistoryRepo{}
+	mail := &fakeMailer{}
+	history := services.NewLoginHistoryService(repo, staticLocator{"198.51.100.20": "DE"}, mail)
+	user := &models.User{Model: gorm.Model{ID: 3}, Name: "Ann", Email: "ann@example.com"}
+	chrome := func(version string) string {
+		return "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/" + version + " Safari/537.36"
+	}
+	login := func(ip, userAgent string, err error) {
+		history.RecordLoginService(ctx, services.LoginAttemptInput{
+			User: user, AuthMethod: "local", Client: dto.ClientInfo{IP: ip, UserAgent: userAgent}, Err: err,
+		})
+	}
+
+	// First login: nothing to compare with, no alert
+	login("203.0.113.5", chrome("120.0.6099.71"), nil)
+	// Same network, browser updated: still the same device
+	login("203.0.113.77", chrome("121.0.6167.85"), nil)
+	// Failed attempts never alert
+	login("192.0.2.1", "curl/8.4.0", errors.New("invalid credentials password or email"))
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, mail.messages())
+
+	// New network and country
+	login("198.51.100.20", chrome("121.0.6167.85"), nil)
+	require.Eventually(t, func() bool { return len(mail.messages()) == 1 }, time.Second, 5*time.Millisecond)
+	alert := mail.messages()[0]
+	assert.Equal(t, "ann@example.com", alert.To)
+	assert.Contains(t, alert.Body, "a new network")
+	assert.NotContains(t, alert.Body, "a new device")
+	assert.Contains(t, alert.Body, "198.51.100.20 (DE)")
+
+	page, err := history.ListLoginsService(ctx, user.ID, dto.PageQuery{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	require.Len(t, page.Logins, 4)
+	assert.True(t, page.Logins[0].NewNetwork)
+	assert.Equal(t, "DE", page.Logins[0].Country)
+	assert.False(t, page.Logins[1].Success)
+	assert.NotEmpty(t, page.Logins[1].Reason)
+	assert.Equal(t, page.Logins[2].Fingerprint, page.Logins[3].Fingerprint)
+	assert.False(t, page.Logins[2].NewDevice)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+)
+
+// OAuthService implements token introspection (RFC 7662) and revocation (RFC 7009)
+type OAuthService interface {
+	AuthenticateClient(clientID, clientSecret string) error
+	IntrospectTokenService(ctx context.Context, token string) (*dto.IntrospectionResponse, error)
+	RevokeTokenService(ctx context.Context, token string) error
+}
+
+// oauthServiceImpl struct implements the OAuthService interface
+type oauthServiceImpl struct {
+	tokenRepo repositories.TokenRepo
+	sessions  SessionService
+	clients   map[string]string // client_id -> client_secret
+}
+
+// NewOAuthService returns an OAuthService that accepts the given client credentials
+func NewOAuthService(repo repositories.TokenRepo, sessions SessionService, clients map[string]string) OAuthService {
+	return &oauthServiceImpl{tokenRepo: repo, sessions: sessions, clients: clients}
+}
+
+// AuthenticateClient checks the client credentials of the calling resource server
+func (s *oauthServiceImpl) AuthenticateClient(clientID, clientSecret string) error {
+	secret, ok := s.clients[clientID]
+	if !ok || clientID == "" {
+		return errors.New("invalid client")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(clientSecret)) != 1 {
+		return errors.New("invalid client")
+	}
+	return nil
+}
+
+// IntrospectTokenService reports whether a token is active and returns its claims
+func (s *oauthServiceImpl) IntrospectTokenService(ctx context.Context, token string) (*dto.IntrospectionResponse, error) {
+	// Step 1: Signature and expiry check; anything invalid is simply inactive
+	claims, err := utils.ValidateJWT(token)
+	if err != nil {
+		return &dto.IntrospectionResponse{Active: false}, nil
+	}
+
+	// Step 2: Check the revocation store
+	if claims.ID != "" {
+		revoked, err := s.tokenRepo.IsTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return &dto.IntrospectionResponse{Active: false}, nil
+		}
+	}
+
+	// Step 3: A token whose session ended (logout, remote sign-out, session limit, deleted user) is
+	// inactive too, as JWTAuthMiddleware rejects it
+	if claims.SessionID != "" {
+		if err := s.sessions.ValidateSessionService(ctx, claims.SessionID); err != nil {
+			if errors.Is(err, ErrSessionTerminated) {
+				return &dto.IntrospectionResponse{Active: false}, nil
+			}
+			return nil, err
+		}
+	}
+
+	// Step 4: Map the claims to the RFC 7662 response
+	resp := &dto.IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		Subject:   claims.Subject,
+		Username:  claims.Email,
+		TokenType: "Bearer",
+		TokenID:   claims.ID,
+		UserID:    claims.UserID,
+		Role:      claims.Role,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return resp, nil
+}
+
+// RevokeTokenService adds the token to the revocation store.
+// Invalid, expired or unknown tokens are ignored as required by RFC 7009.
+func (s *oauthServiceImpl) RevokeTokenService(ctx context.Context, token string) error {
+	claims, err := utils.ValidateJWT(token)
+	if err != nil || claims.ID == "" {
+		return nil
+	}
+
+	revoked := &models.RevokedToken{
+		JTI:    claims.ID,
+		UserID: claims.UserID,
+	}
+	if claims.ExpiresAt != nil {
+		revoked.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if err := s.tokenRepo.RevokeToken(ctx, revoked); err != nil {
+		return err
+	}
+	countRevocation(unknownAuthMethod, "oauth") // the token doesn't say how its user logged in
+	return nil
+}
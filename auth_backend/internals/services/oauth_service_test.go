@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeTokenRepo is an in-memory TokenRepo
+type fakeTokenRepo struct {
+	mu      sync.Mutex
+	revoked map[string]models.RevokedToken
+}
+
+func (r *fakeTokenRepo) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.revoked == nil {
+		r.revoked = map[string]models.RevokedToken{}
+	}
+	r.revoked[token.JTI] = *token
+	return nil
+}
+
+func (r *fakeTokenRepo) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+// expiredJWT returns a well signed token that expired an hour ago
+func expiredJWT(t *testing.T) string {
+	claims := utils.CustomClaims{
+		UserID: 2,
+		Email:  "ann@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "expired-jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-2 * time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	require.NoError(t, err)
+	return token
+}
+
+// TestOAuthAuthenticateClient checks the client credentials check
+func TestOAuthAuthenticateClient(t *testing.T) {
+	oauth := services.NewOAuthService(&fakeTokenRepo{}, nil, map[string]string{"api": "s3cret"})
+
+	assert.NoError(t, oauth.AuthenticateClient("api", "s3cret"))
+	assert.Error(t, oauth.AuthenticateClient("api", "wrong"))
+	assert.Error(t, oauth.AuthenticateClient("other", "s3cret"))
+	assert.Error(t, oauth.AuthenticateClient("", ""))
+}
+
+// TestOAuthIntrospectAndRevoke checks active and inactive tokens, and that a revoked jti is inactive
+func TestOAuthIntrospectAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeTokenRepo{}
+	oauth := services.NewOAuthService(repo, nil, nil) // the token has no session
+	token, err := utils.GenerateJWT(2, "ann@example.com", "admin", "")
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token)
+	require.NoError(t, err)
+
+	resp, err := oauth.IntrospectTokenService(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, resp.Active)
+	assert.Equal(t, "2", resp.Subject)
+	assert.Equal(t, "ann@example.com", resp.Username)
+	assert.Equal(t, "admin", resp.Role)
+	assert.Equal(t, claims.ID, resp.TokenID)
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, claims.ExpiresAt.Unix(), resp.ExpiresAt)
+
+	// Invalid and expired tokens are inactive, with nothing else said about them
+	for _, inactive := range []string{"not-a-jwt", expiredJWT(t), token + "x"} {
+		resp, err := oauth.IntrospectTokenService(ctx, inactive)
+		require.NoError(t, err)
+		assert.False(t, resp.Active)
+		assert.Empty(t, resp.Username)
+	}
+
+	// Revoking stores the jti, after which the token is inactive
+	require.NoError(t, oauth.RevokeTokenService(ctx, token))
+	assert.Contains(t, repo.revoked, claims.ID)
+	assert.Equal(t, claims.ExpiresAt.Time, repo.revoked[claims.ID].ExpiresAt)
+	resp, err = oauth.IntrospectTokenService(ctx, token)
+	require.NoError(t, err)
+	assert.False(t, resp.Active)
+	require.NoError(t, oauth.RevokeTokenService(ctx, token), "revoking twice is fine")
+
+	// Unknown, invalid and expired tokens are ignored (RFC 7009)
+	for _, unknown := range []string{"not-a-jwt", expiredJWT(t)} {
+		require.NoError(t, oauth.RevokeTokenService(ctx, unknown))
+	}
+	assert.Len(t, repo.revoked, 1)
+}
+
+// TestOAuthIntrospectEndedSession checks that the token of a session that was signed out is
+// inactive, as it is for JWTAuthMiddleware
+func TestOAuthIntrospectEndedSession(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	oauth := services.NewOAuthService(&fakeTokenRepo{}, sessions, nil)
+	user := &models.User{Model: gorm.Model{ID: 2}, Email: "ann@example.com", Role: "user"}
+	token, err := sessions.StartSessionService(ctx, user, dto.ClientInfo{}, "local")
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token)
+	require.NoError(t, err)
+
+	resp, err := oauth.IntrospectTokenService(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, resp.Active)
+
+	require.NoError(t, sessions.RevokeSessionService(ctx, user.ID, claims.SessionID))
+	resp, err = oauth.IntrospectTokenService(ctx, token)
+	require.NoError(t, err)
+	assert.False(t, resp.Active)
+	assert.Empty(t, resp.Username)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/pkg/config"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// unusablePassword returns a bcrypt hash of a random password nobody knows.
+// Accounts managed by an external identity source get one so local password login is impossible.
+func unusablePassword() (string, error) {
+	randomPassword, err := utils.RandomString(32)
+	if err != nil {
+		return "", errors.New("failed to generate password")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+	return string(hashedPassword), nil
+}
+
+// mapGroupsToRole returns the role of the first configured group the user is a member of
+func mapGroupsToRole(mappings []config.GroupRole, groups []string, defaultRole string) string {
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if strings.EqualFold(group, mapping.Group) {
+				return mapping.Role
+			}
+		}
+	}
+	return defaultRole
+}
+
+// ErrAccountNotLinked refuses an external login whose email belongs to an account the identity
+// source didn't provision, e.g. a local one: the email alone doesn't prove it is the same person
+var ErrAccountNotLinked = NewError(ErrForbidden, "account_not_linked", "an account with this email exists and is not managed by this identity source")
+
+// provisionUser creates the local user on first login (just in time) and keeps name and role in
+// sync with the external identity source on later logins. Only users the source provisioned
+// itself are synced; role changes are audited.
+func provisionUser(ctx context.Context, repo repositories.UserRepo, audit AuditService, source, email, name, role string) (*models.User, error) {
+	if name == "" {
+		name = email
+	}
+
+	user, err := repo.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		password, err := unusablePassword()
+		if err != nil {
+			return nil, err
+		}
+
+		// The identity source vouches for the email
+		log.Printf("Provisioning %s user %s with role %s", source, email, role)
+		verifiedAt := time.Now()
+		user, err := repo.CreateUser(ctx, &models.User{
+			Name:            name,
+			Email:           email,
+			Password:        password,
+			Role:            role,
+			EmailVerifiedAt: &verifiedAt,
+			AuthSource:      source,
+		})
+		if err != nil {
+			return nil, err
+		}
+		countRegistration(source)
+		audit.Record(ctx, AuditEntry{
+			Type:       EventUserRegistered,
+			Actor:      dto.Actor{ID: user.ID, Email: user.Email},
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+			Metadata:   map[string]any{"role": user.Role, "auth_source": source},
+		})
+		return user, nil
+	}
+
+	if user.AuthSource != source {
+		return nil, ErrAccountNotLinked
+	}
+	if user.Name == name && user.Role == role {
+		return user, nil
+	}
+
+	previousRole := user.Role
+	user.Name = name
+	user.Role = role
+	updated, err := repo.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if updated.Role != previousRole {
+		audit.Record(ctx, AuditEntry{
+			Type:       EventRoleChanged,
+			Actor:      dto.Actor{ID: updated.ID, Email: updated.Email},
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(updated.ID), 10),
+			Metadata:   map[string]any{"from": previousRole, "to": updated.Role, "auth_source": source},
+		})
+	}
+	return updated, nil
+}
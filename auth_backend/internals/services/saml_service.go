@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/pkg/config"
+)
+
+// Errors of the SAML assertion consumer service
+var (
+	ErrInvalidSAMLResponse = NewError(ErrUnauthorized, "invalid_saml_response", "invalid SAML response")
+	ErrSAMLNoEmail         = NewError(ErrUnauthorized, "saml_missing_email", "SAML assertion has no email")
+)
+
+// SAMLService implements the SAML 2.0 service provider (SP) side of enterprise SSO
+type SAMLService interface {
+	MetadataService() ([]byte, error)
+	BeginLoginService(relayState string) (string, string, error)
+	CompleteLoginService(ctx context.Context, r *http.Request, possibleRequestIDs []string, client dto.ClientInfo) (*dto.LoginResponse, string, error)
+}
+
+// samlServiceImpl struct implements the SAMLService interface
+type samlServiceImpl struct {
+	sp       *saml.ServiceProvider
+	cfg      config.SAMLConfig
+	userRepo repositories.UserRepo
+	sessions SessionService
+	history  LoginHistoryService
+	audit    AuditService
+}
+
+// NewSAMLService returns a SAMLService for an already configured service provider
+func NewSAMLService(sp *saml.ServiceProvider, cfg config.SAMLConfig, repo repositories.UserRepo, sessions SessionService, history LoginHistoryService, audit AuditService) SAMLService {
+	return &samlServiceImpl{sp: sp, cfg: cfg, userRepo: repo, sessions: sessions, history: history, audit: audit}
+}
+
+// NewSAMLServiceProvider loads the SP key pair and the IdP metadata from the configuration
+func NewSAMLServiceProvider(cfg config.SAMLConfig) (*saml.ServiceProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SAML key pair: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("SAML key must be an RSA private key")
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SAML certificate: %w", err)
+	}
+
+	var idpMetadata *saml.EntityDescriptor
+	if cfg.IDPMetadataFile != "" {
+		data, err := os.ReadFile(cfg.IDPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IdP metadata: %w", err)
+		}
+		idpMetadata, err = samlsp.ParseMetadata(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IdP metadata: %w", err)
+		}
+	} else {
+		metadataURL, err := url.Parse(cfg.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IdP metadata URL: %w", err)
+		}
+		idpMetadata, err = samlsp.FetchMetadata(context.Background(), http.DefaultClient, *metadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch IdP metadata: %w", err)
+		}
+	}
+
+	metadataURL, err := url.Parse(cfg.RootURL + "/metadata")
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML root URL: %w", err)
+	}
+	acsURL, _ := url.Parse(cfg.RootURL + "/acs")
+
+	return &saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		Key:               key,
+		Certificate:       cert,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AllowIDPInitiated: cfg.AllowIDPInitiated,
+	}, nil
+}
+
+// MetadataService returns the SP metadata XML to register at the IdP
+func (s *samlServiceImpl) MetadataService() ([]byte, error) {
+	return xml.MarshalIndent(s.sp.Metadata(), "", "  ")
+}
+
+// BeginLoginService returns the IdP redirect URL (HTTP-Redirect binding) and the AuthnRequest ID
+func (s *samlServiceImpl) BeginLoginService(relayState string) (string, string, error) {
+	ssoURL := s.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		return "", "", errors.New("IdP has no HTTP-Redirect SSO endpoint")
+	}
+
+	req, err := s.sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create AuthnRequest: %w", err)
+	}
+
+	redirectURL, err := req.Redirect(relayState, s.sp)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode AuthnRequest: %w", err)
+	}
+	return redirectURL.String(), req.ID, nil
+}
+
+// CompleteLoginService validates the signed assertion posted to the ACS and logs the user in
+func (s *samlServiceImpl) CompleteLoginService(ctx context.Context, r *http.Request, possibleRequestIDs []string, client dto.ClientInfo) (_ *dto.LoginResponse, _ string, err error) {
+	var (
+		user  *models.User
+		email string
+	)
+	defer func() {
+		countLogin("saml", err)
+		recordLogin(ctx, s.audit, user, email, "saml", client, err)
+	}()
+
+	// Step 1: Signature, audience, time window and InResponseTo checks
+	if err := r.ParseForm(); err != nil {
+		return nil, "", ErrInvalidSAMLResponse.Wrap(err)
+	}
+	assertion, err := s.sp.ParseResponse(r, possibleRequestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, "", ErrInvalidSAMLResponse.Wrap(invalid.PrivateErr)
+		}
+		return nil, "", ErrInvalidSAMLResponse.Wrap(err)
+	}
+
+	// Step 2: Map the configured attributes to the user
+	email = samlAttribute(assertion, s.cfg.EmailAttribute)
+	if email == "" && assertion.Subject != nil && assertion.Subject.NameID != nil && strings.Contains(assertion.Subject.NameID.Value, "@") {
+		email = assertion.Subject.NameID.Value
+	}
+	if email == "" {
+		return nil, "", ErrSAMLNoEmail
+	}
+	role := mapGroupsToRole(s.cfg.GroupRoles, samlAttributeValues(assertion, s.cfg.GroupsAttribute), s.cfg.DefaultRole)
+
+	// Step 3: Just-in-time provisioning
+	if user, err = provisionUser(ctx, s.userRepo, s.audit, "saml", email, samlAttribute(assertion, s.cfg.NameAttribute), role); err != nil {
+		return nil, "", err
+	}
+
+	// Step 4: Same session and token as a password login
+	token, err := s.sessions.StartSessionService(ctx, user, client, "saml")
+	s.history.RecordLoginService(ctx, LoginAttemptInput{User: user, AuthMethod: "saml", Client: client, Err: err})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &dto.LoginResponse{
+		ID:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+		Age:   user.Age,
+		Role:  user.Role,
+	}, token, nil
+}
+
+// samlAttribute returns the first value of an attribute matched by name or friendly name
+func samlAttribute(assertion *saml.Assertion, name string) string {
+	if values := samlAttributeValues(assertion, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// samlAttributeValues returns all values of an attribute matched by name or friendly name
+func samlAttributeValues(assertion *saml.Assertion, name string) []string {
+	var values []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if attr.Name != name && attr.FriendlyName != name {
+				continue
+			}
+			for _, v := range attr.Values {
+				values = append(values, v.Value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,203 @@
+package services_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKeyPair returns an RSA key with a self signed certificate for fixture signing
+func newTestKeyPair(t *testing.T, cn string) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+// staticSPProvider lets the test IdP know our SP metadata
+type staticSPProvider struct {
+	metadata *saml.EntityDescriptor
+}
+
+func (p staticSPProvider) GetServiceProvider(_ *http.Request, _ string) (*saml.EntityDescriptor, error) {
+	return p.metadata, nil
+}
+
+// samlFixture wires a local IdP that signs assertions to the SP under test
+type samlFixture struct {
+	idp     *saml.IdentityProvider
+	service services.SAMLService
+	repo    repositories.UserRepo
+	audit   *fakeAuditRepo
+}
+
+func newSAMLFixture(t *testing.T) *samlFixture {
+	idpKey, idpCert := newTestKeyPair(t, "idp.example.com")
+	spKey, spCert := newTestKeyPair(t, "sp.example.com")
+
+	idpMetadataURL, _ := url.Parse("https://idp.example.com/metadata")
+	idpSSOURL, _ := url.Parse("https://idp.example.com/sso")
+	idp := &saml.IdentityProvider{
+		Key:         idpKey,
+		Certificate: idpCert,
+		MetadataURL: *idpMetadataURL,
+		SSOURL:      *idpSSOURL,
+	}
+
+	cfg := config.SAMLConfig{
+		RootURL:         "http://localhost:8080/api/v1/saml",
+		EmailAttribute:  "email",
+		NameAttribute:   "displayName",
+		GroupsAttribute: "groups",
+		GroupRoles:      []config.GroupRole{{Group: "auth-admins", Role: "admin"}},
+		DefaultRole:     "user",
+	}
+	spMetadataURL, _ := url.Parse(cfg.RootURL + "/metadata")
+	acsURL, _ := url.Parse(cfg.RootURL + "/acs")
+	sp := &saml.ServiceProvider{
+		Key:         spKey,
+		Certificate: spCert,
+		MetadataURL: *spMetadataURL,
+		AcsURL:      *acsURL,
+		IDPMetadata: idp.Metadata(),
+	}
+	idp.ServiceProviderProvider = staticSPProvider{metadata: sp.Metadata()}
+
+	repo := repositories.NewMemoryUserRepo()
+	auditRepo := &fakeAuditRepo{}
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	service := services.NewSAMLService(sp, cfg, repo, sessions, newTestLoginHistory(), services.NewAuditService(auditRepo, config.AuditConfig{}))
+	return &samlFixture{idp: idp, service: service, repo: repo, audit: auditRepo}
+}
+
+// signedResponse lets the IdP answer the AuthnRequest in redirectURL and returns the ACS form post
+func (f *samlFixture) signedResponse(t *testing.T, redirectURL string, groups ...string) *http.Request {
+	idpReq, err := saml.NewIdpAuthnRequest(f.idp, httptest.NewRequest(http.MethodGet, redirectURL, nil))
+	require.NoError(t, err)
+	require.NoError(t, idpReq.Validate())
+
+	session := &saml.Session{
+		ID:         "session-1",
+		CreateTime: time.Now(),
+		ExpireTime: time.Now().Add(time.Hour),
+		NameID:     "jane@example.com",
+		CustomAttributes: []saml.Attribute{
+			{Name: "email", Values: []saml.AttributeValue{{Type: "xs:string", Value: "jane@example.com"}}},
+			{Name: "displayName", Values: []saml.AttributeValue{{Type: "xs:string", Value: "Jane Doe"}}},
+		},
+	}
+	if len(groups) > 0 {
+		attr := saml.Attribute{Name: "groups"}
+		for _, g := range groups {
+			attr.Values = append(attr.Values, saml.AttributeValue{Type: "xs:string", Value: g})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, attr)
+	}
+
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(idpReq, session))
+	require.NoError(t, idpReq.MakeResponse())
+
+	doc := etree.NewDocument()
+	doc.SetRoot(idpReq.ResponseEl)
+	raw, err := doc.WriteToBytes()
+	require.NoError(t, err)
+
+	return acsRequest(base64.StdEncoding.EncodeToString(raw))
+}
+
+func acsRequest(samlResponse string) *http.Request {
+	form := url.Values{"SAMLResponse": {samlResponse}}
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/api/v1/saml/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// TestSAMLMetadata checks that the SP metadata advertises the ACS endpoint
+func TestSAMLMetadata(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	metadata, err := f.service.MetadataService()
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), "http://localhost:8080/api/v1/saml/acs")
+}
+
+// TestSAMLLoginProvisionsUser runs AuthnRequest -> signed assertion -> ACS
+func TestSAMLLoginProvisionsUser(t *testing.T) {
+	ctx := context.Background()
+	f := newSAMLFixture(t)
+
+	redirectURL, requestID, err := f.service.BeginLoginService("")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(redirectURL, "https://idp.example.com/sso?SAMLRequest="))
+
+	resp, token, err := f.service.CompleteLoginService(ctx, f.signedResponse(t, redirectURL, "auth-admins"), []string{requestID}, dto.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "jane@example.com", resp.Email)
+	assert.Equal(t, "Jane Doe", resp.Name)
+	assert.Equal(t, "admin", resp.Role)
+	assert.Len(t, allUsers(t, f.repo), 1)
+	assert.Equal(t, []string{services.EventUserRegistered, services.EventLoginSucceeded}, f.audit.types())
+}
+
+// TestSAMLRejectsUnknownRequestID checks that responses to someone else's AuthnRequest are refused
+func TestSAMLRejectsUnknownRequestID(t *testing.T) {
+	ctx := context.Background()
+	f := newSAMLFixture(t)
+
+	redirectURL, _, err := f.service.BeginLoginService("")
+	require.NoError(t, err)
+
+	_, _, err = f.service.CompleteLoginService(ctx, f.signedResponse(t, redirectURL), []string{"id-other"}, dto.ClientInfo{})
+	assert.Error(t, err)
+	assert.Empty(t, allUsers(t, f.repo))
+	assert.Equal(t, []string{services.EventLoginFailed}, f.audit.types())
+}
+
+// TestSAMLRejectsTamperedResponse checks that the signature covers the response
+func TestSAMLRejectsTamperedResponse(t *testing.T) {
+	ctx := context.Background()
+	f := newSAMLFixture(t)
+
+	redirectURL, requestID, err := f.service.BeginLoginService("")
+	require.NoError(t, err)
+
+	req := f.signedResponse(t, redirectURL)
+	require.NoError(t, req.ParseForm())
+	raw, err := base64.StdEncoding.DecodeString(req.PostForm.Get("SAMLResponse"))
+	require.NoError(t, err)
+	tampered := strings.Replace(string(raw), `Version="2.0"`, `Version="2.0" Consent="tampered"`, 1)
+
+	_, _, err = f.service.CompleteLoginService(ctx, acsRequest(base64.StdEncoding.EncodeToString([]byte(tampered))), []string{requestID}, dto.ClientInfo{})
+	assert.Error(t, err)
+	assert.Empty(t, allUsers(t, f.repo))
+}
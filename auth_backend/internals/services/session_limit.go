@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+	"github.com/devesh121/userAuth/pkg/config"
+)
+
+// ErrSessionLimitReached is returned when the reject policy refuses a login
+var ErrSessionLimitReached = NewError(ErrForbidden, "session_limit_reached", "too many active sessions, sign out on another device first")
+
+// enforceSessionLimit applies the concurrent session policy before a new session of user is created.
+// With the evict policy the oldest sessions are signed out until the new one fits. repo must hold
+// the lock of the user's sessions until the new one is created, so the audit entries are returned
+// for the caller to record once the lock is released.
+func (s *sessionServiceImpl) enforceSessionLimit(ctx context.Context, repo repositories.SessionRepo, user *models.User, client dto.ClientInfo) ([]AuditEntry, error) {
+	limit := s.limits.LimitFor(user.Role)
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	active, err := repo.ListActiveSessionsByUser(ctx, user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	excess := len(active) - limit + 1
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	actor := dto.Actor{ID: user.ID, Email: user.Email, ClientInfo: client}
+	if s.limits.Policy == config.SessionLimitReject {
+		metrics.SessionLimitEnforcementsTotal.WithLabelValues(user.Role, "rejected").Inc()
+		return []AuditEntry{{
+			Type:       EventSessionLimitRejected,
+			Actor:      actor,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+			Metadata:   map[string]any{"role": user.Role, "active": len(active), "limit": limit},
+		}}, ErrSessionLimitReached
+	}
+
+	// Oldest first
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
+	var entries []AuditEntry
+	for _, session := range active[:excess] {
+		if err := repo.RevokeSession(ctx, session.ID, now); err != nil {
+			return nil, err
+		}
+		metrics.SessionLimitEnforcementsTotal.WithLabelValues(user.Role, "evicted").Inc()
+		countRevocation(session.AuthMethod, "session_limit")
+		entries = append(entries, AuditEntry{
+			Type:       EventSessionEvicted,
+			Actor:      actor,
+			TargetType: "session",
+			TargetID:   session.ID,
+			Metadata: map[string]any{
+				"role":       user.Role,
+				"limit":      limit,
+				"created_at": session.CreatedAt.UTC().Format(time.RFC3339),
+				"ip":         session.IP,
+				"user_agent": session.UserAgent,
+			},
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+	"github.com/devesh121/userAuth/pkg/config"
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval limits how often LastSeenAt is written for busy sessions
+const sessionTouchInterval = time.Minute
+
+// ErrSessionTerminated is returned for tokens whose session was signed out or has expired
+var ErrSessionTerminated = NewError(ErrUnauthorized, "session_terminated", "session terminated")
+
+// ErrSessionNotFound is returned for sessions that don't exist or belong to another user
+var ErrSessionNotFound = NewError(ErrNotFound, "session_not_found", "session not found")
+
+// SessionService manages login sessions (one per login on a device)
+type SessionService interface {
+	StartSessionService(ctx context.Context, user *models.User, client dto.ClientInfo, authMethod string) (string, error)
+	ValidateSessionService(ctx context.Context, sessionID string) error
+	ListSessionsService(ctx context.Context, userID uint, currentSessionID string) ([]dto.SessionResponse, error)
+	RevokeSessionService(ctx context.Context, userID uint, sessionID string) error
+	RevokeOtherSessionsService(ctx context.Context, userID uint, currentSessionID string) (int64, error)
+	CountActiveUsersService(ctx context.Context) (int64, error)
+	RunActiveUsersGauge(ctx context.Context, interval time.Duration)
+}
+
+// sessionServiceImpl struct implements the SessionService interface
+type sessionServiceImpl struct {
+	sessionRepo repositories.SessionRepo
+	limits      config.SessionLimitConfig // concurrent session limits, enforced on login
+	audit       AuditService
+}
+
+// NewSessionService returns implementation of SessionService
+func NewSessionService(repo repositories.SessionRepo, limits config.SessionLimitConfig, audit AuditService) SessionService {
+	return &sessionServiceImpl{sessionRepo: repo, limits: limits, audit: audit}
+}
+
+// StartSessionService persists a new session and returns the token bound to it
+func (s *sessionServiceImpl) StartSessionService(ctx context.Context, user *models.User, client dto.ClientInfo, authMethod string) (string, error) {
+	sessionID, err := utils.RandomString(24)
+	if err != nil {
+		return "", errors.New("failed to generate session id")
+	}
+
+	// Logins of the same user take turns, so concurrent ones can't all fit under the limit
+	var entries []AuditEntry
+	err = s.sessionRepo.LockUserSessions(ctx, user.ID, func(repo repositories.SessionRepo) error {
+		// Make room for the new session or refuse it, depending on the policy
+		var err error
+		if entries, err = s.enforceSessionLimit(ctx, repo, user, client); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		_, err = repo.CreateSession(ctx, &models.Session{
+			ID:         sessionID,
+			UserID:     user.ID,
+			AuthMethod: authMethod,
+			UserAgent:  truncate(client.UserAgent, 512),
+			IP:         client.IP,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(utils.TokenTTL),
+		})
+		if err != nil {
+			entries = nil // the evictions are rolled back with it
+			return errors.New("failed to create session")
+		}
+		return nil
+	})
+	// Recorded outside the lock, the audit log has a lock of its own
+	for _, entry := range entries {
+		s.audit.Record(ctx, entry)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role, sessionID)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+	return token, nil
+}
+
+// ValidateSessionService checks that a session is still live and records activity on it
+func (s *sessionServiceImpl) ValidateSessionService(ctx context.Context, sessionID string) error {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionTerminated
+		}
+		return err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return ErrSessionTerminated
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.sessionRepo.TouchSession(ctx, session.ID, now); err != nil {
+			log.Printf("failed to update last seen for session %s: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// ListSessionsService returns the live sessions of a user, flagging the current one
+func (s *sessionServiceImpl) ListSessionsService(ctx context.Context, userID uint, currentSessionID string) ([]dto.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveSessionsByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, dto.SessionResponse{
+			ID:         session.ID,
+			AuthMethod: session.AuthMethod,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return responses, nil
+}
+
+// RevokeSessionService signs out one of the user's sessions
+func (s *sessionServiceImpl) RevokeSessionService(ctx context.Context, userID uint, sessionID string) error {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil || session.UserID != userID {
+		// Don't reveal sessions of other users
+		return ErrSessionNotFound
+	}
+	now := time.Now()
+	if err := s.sessionRepo.RevokeSession(ctx, session.ID, now); err != nil {
+		return err
+	}
+	if session.RevokedAt == nil && now.Before(session.ExpiresAt) {
+		countRevocation(session.AuthMethod, "user")
+	}
+	return nil
+}
+
+// RevokeOtherSessionsService signs out everywhere except the current session ("" signs out
+// everywhere)
+func (s *sessionServiceImpl) RevokeOtherSessionsService(ctx context.Context, userID uint, currentSessionID string) (int64, error) {
+	// The live sessions are listed first to count them by auth method
+	now := time.Now()
+	active, err := s.sessionRepo.ListActiveSessionsByUser(ctx, userID, now)
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := s.sessionRepo.RevokeOtherSessions(ctx, userID, currentSessionID, now)
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range active {
+		if session.ID != currentSessionID {
+			countRevocation(session.AuthMethod, "user")
+		}
+	}
+	return revoked, nil
+}
+
+// CountActiveUsersService returns the number of users with a live session
+func (s *sessionServiceImpl) CountActiveUsersService(ctx context.Context) (int64, error) {
+	return s.sessionRepo.CountActiveUsers(ctx, time.Now())
+}
+
+// RunActiveUsersGauge sets metrics.ActiveUsers every interval until ctx is done, starting right away
+func (s *sessionServiceImpl) RunActiveUsersGauge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if count, err := s.CountActiveUsersService(ctx); err != nil {
+			log.Printf("failed to count active users: %v", err)
+		} else {
+			metrics.ActiveUsers.Set(float64(count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// truncate cuts s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,228 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeSessionRepo is an in-memory SessionRepo
+type fakeSessionRepo struct {
+	mu       sync.Mutex
+	userLock sync.Mutex // held by LockUserSessions, one lock for all users
+	sessions map[string]*models.Session
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{sessions: map[string]*models.Session{}}
+}
+
+func (r *fakeSessionRepo) CreateSession(ctx context.Context, session *models.Session) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.CreatedAt = time.Now()
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return session, nil
+}
+
+func (r *fakeSessionRepo) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *fakeSessionRepo) ListActiveSessionsByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepo) RevokeSession(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok && session.RevokedAt == nil {
+		session.RevokedAt = &at
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeOtherSessions(ctx context.Context, userID uint, keepID string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for id, session := range r.sessions {
+		if session.UserID == userID && id != keepID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeSessionRepo) TouchSession(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = at
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) CountActiveUsers(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := map[uint]bool{}
+	for _, session := range r.sessions {
+		if session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			users[session.UserID] = true
+		}
+	}
+	return int64(len(users)), nil
+}
+
+func (r *fakeSessionRepo) LockUserSessions(ctx context.Context, userID uint, fn func(repo repositories.SessionRepo) error) error {
+	r.userLock.Lock()
+	defer r.userLock.Unlock()
+	return fn(r)
+}
+
+// startSession logs the user in on a device and returns the session ID from the token
+func startSession(t *testing.T, svc services.SessionService, user *models.User, userAgent string) string {
+	token, err := svc.StartSessionService(context.Background(), user, dto.ClientInfo{IP: "203.0.113.7", UserAgent: userAgent}, "local")
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.SessionID)
+	return claims.SessionID
+}
+
+// TestSessionLifecycle checks listing, remote sign-out and "sign out everywhere else"
+func TestSessionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	alice := &models.User{Model: gorm.Model{ID: 1}, Email: "alice@example.com", Role: "user"}
+	bob := &models.User{Model: gorm.Model{ID: 2}, Email: "bob@example.com", Role: "user"}
+
+	laptop := startSession(t, svc, alice, "laptop")
+	phone := startSession(t, svc, alice, "phone")
+	tablet := startSession(t, svc, alice, "tablet")
+	bobs := startSession(t, svc, bob, "desktop")
+
+	sessions, err := svc.ListSessionsService(ctx, alice.ID, laptop)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == laptop, session.Current)
+		assert.Equal(t, "203.0.113.7", session.IP)
+	}
+
+	// Remote sign-out of one device; other users' sessions can't be touched
+	require.NoError(t, svc.RevokeSessionService(ctx, alice.ID, phone))
+	assert.ErrorIs(t, svc.ValidateSessionService(ctx, phone), services.ErrSessionTerminated)
+	assert.Error(t, svc.RevokeSessionService(ctx, alice.ID, bobs))
+	assert.NoError(t, svc.ValidateSessionService(ctx, bobs))
+
+	// Sign out everywhere else keeps the current session only
+	count, err := svc.RevokeOtherSessionsService(ctx, alice.ID, laptop)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, svc.ValidateSessionService(ctx, laptop))
+	assert.ErrorIs(t, svc.ValidateSessionService(ctx, tablet), services.ErrSessionTerminated)
+	assert.ErrorIs(t, svc.ValidateSessionService(ctx, "unknown"), services.ErrSessionTerminated)
+}
+
+// TestSessionLimitPolicies checks the evict-oldest and reject policies with per role limits
+func TestSessionLimitPolicies(t *testing.T) {
+	ctx := context.Background()
+	limits := config.SessionLimitConfig{DefaultLimit: 2, RoleLimits: map[string]int{"admin": 1}}
+	admin := &models.User{Model: gorm.Model{ID: 1}, Email: "admin@example.com", Role: "admin"}
+	user := &models.User{Model: gorm.Model{ID: 2}, Email: "user@example.com", Role: "user"}
+
+	// Evict oldest: the new login always succeeds and the oldest session is signed out
+	limits.Policy = config.SessionLimitEvictOldest
+	svc := services.NewSessionService(newFakeSessionRepo(), limits, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	first := startSession(t, svc, admin, "laptop")
+	time.Sleep(time.Millisecond)
+	second := startSession(t, svc, admin, "phone")
+	assert.ErrorIs(t, svc.ValidateSessionService(ctx, first), services.ErrSessionTerminated)
+	assert.NoError(t, svc.ValidateSessionService(ctx, second))
+
+	oldest := startSession(t, svc, user, "laptop")
+	time.Sleep(time.Millisecond)
+	middle := startSession(t, svc, user, "phone")
+	time.Sleep(time.Millisecond)
+	newest := startSession(t, svc, user, "tablet")
+	assert.ErrorIs(t, svc.ValidateSessionService(ctx, oldest), services.ErrSessionTerminated)
+	assert.NoError(t, svc.ValidateSessionService(ctx, middle))
+	assert.NoError(t, svc.ValidateSessionService(ctx, newest))
+
+	// Reject: the new login fails and existing sessions are untouched
+	limits.Policy = config.SessionLimitReject
+	svc = services.NewSessionService(newFakeSessionRepo(), limits, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	kept := startSession(t, svc, admin, "laptop")
+	_, err := svc.StartSessionService(ctx, admin, dto.ClientInfo{}, "local")
+	assert.ErrorIs(t, err, services.ErrSessionLimitReached)
+	assert.NoError(t, svc.ValidateSessionService(ctx, kept))
+
+	// Signing out frees a slot
+	require.NoError(t, svc.RevokeSessionService(ctx, admin.ID, kept))
+	startSession(t, svc, admin, "phone")
+}
+
+// TestSessionLimitConcurrentLogins checks that logins racing each other can't exceed the limit
+func TestSessionLimitConcurrentLogins(t *testing.T) {
+	ctx := context.Background()
+	limits := config.SessionLimitConfig{DefaultLimit: 3, Policy: config.SessionLimitReject}
+	repo := newFakeSessionRepo()
+	svc := services.NewSessionService(repo, limits, services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{}))
+	user := &models.User{Model: gorm.Model{ID: 2}, Email: "user@example.com", Role: "user"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.StartSessionService(ctx, user, dto.ClientInfo{}, "local")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, services.ErrSessionLimitReached)
+		}
+	}
+	assert.Equal(t, 3, succeeded)
+	active, err := repo.ListActiveSessionsByUser(ctx, user.ID, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, active, 3)
+}
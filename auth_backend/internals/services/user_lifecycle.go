@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/pkg/config"
+	"gorm.io/gorm"
+)
+
+// Errors of restoring a deleted user
+var (
+	ErrDeletedUserNotFound = NewError(ErrNotFound, "deleted_user_not_found", "no restorable deleted user with this id")
+	ErrRestoreEmailTaken   = NewError(ErrConflict, "email_taken", "another user has registered with this email since, change theirs first")
+)
+
+// purgeBatchSize bounds the users purged in one transaction
+const purgeBatchSize = 500
+
+// ListDeletedUsersService returns one page of the soft-deleted users an admin can restore
+func (s *userServiceImpl) ListDeletedUsersService(ctx context.Context, query dto.DeletedUserQuery) (*dto.DeletedUserPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultUserPageSize
+	}
+
+	users, total, err := s.userRepo.ListDeletedUsers(ctx, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.DeletedUserPage{Users: make([]dto.DeletedUserResponse, 0, len(users)), Total: total, Limit: limit, Offset: query.Offset}
+	for i := range users {
+		page.Users = append(page.Users, dto.DeletedUserResponse{
+			UserResponse: newUserResponse(&users[i]),
+			DeletedAt:    users[i].DeletedAt.Time,
+		})
+	}
+	return page, nil
+}
+
+// RestoreUserService brings back a soft-deleted user, as long as nobody took the email meanwhile
+func (s *userServiceImpl) RestoreUserService(ctx context.Context, id uint, actor dto.Actor) (*dto.UserResponse, error) {
+	user, err := s.userRepo.RestoreUser(ctx, id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrDeletedUserNotFound.Wrap(err)
+	case errors.Is(err, repositories.ErrDuplicateEmail):
+		return nil, ErrRestoreEmailTaken.Wrap(err)
+	case err != nil:
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventUserRestored,
+		Actor:      actor,
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Metadata:   map[string]any{"email": user.Email},
+	})
+
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// UserPurgeService removes the users deleted longer ago than the retention period
+type UserPurgeService interface {
+	PurgeService(ctx context.Context) (int, error)
+	RunPurges(ctx context.Context)
+}
+
+// userPurgeServiceImpl struct implements the UserPurgeService interface
+type userPurgeServiceImpl struct {
+	userRepo repositories.UserRepo
+	audit    AuditService
+	cfg      config.UserPurgeConfig // retention, interval and mode
+}
+
+// NewUserPurgeService returns implementation of UserPurgeService
+func NewUserPurgeService(repo repositories.UserRepo, audit AuditService, cfg config.UserPurgeConfig) UserPurgeService {
+	return &userPurgeServiceImpl{userRepo: repo, audit: audit, cfg: cfg}
+}
+
+// PurgeService deletes or anonymizes every user deleted before now minus the retention, in
+// batches, and returns how many were purged
+func (s *userPurgeServiceImpl) PurgeService(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.cfg.Retention)
+	mode := repositories.PurgeMode(s.cfg.Mode)
+
+	purged := 0
+	for {
+		users, err := s.userRepo.PurgeDeletedUsers(ctx, before, mode, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		// The audit log keeps that it happened, not who the user was
+		for _, user := range users {
+			s.audit.Record(ctx, AuditEntry{
+				Type:       EventUserPurged,
+				TargetType: "user",
+				TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+				Metadata:   map[string]any{"mode": s.cfg.Mode, "deleted_at": user.DeletedAt.Time},
+			})
+		}
+		purged += len(users)
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// RunPurges purges every Interval until ctx is done, starting right away
+func (s *userPurgeServiceImpl) RunPurges(ctx context.Context) {
+	if s.cfg.Retention == 0 {
+		log.Println("user purge disabled: USER_RETENTION_DAYS is not set")
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := s.PurgeService(ctx); err != nil {
+			log.Printf("failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted users (%s)", purged, s.cfg.Mode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRestoreAndPurgeUsers checks restore errors and that the purge only touches users past retention
+func TestRestoreAndPurgeUsers(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := &fakeAuditRepo{}
+	audit := services.NewAuditService(auditRepo, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), newTestEmailService(repo, audit), newTestAPIKeyService(repo))
+	admin := dto.Actor{ID: 99, Email: "admin@example.com"}
+
+	ann, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	require.NoError(t, userService.DeleteUserService(ctx, ann.ID, admin))
+
+	// The email is free again, so Ann can't come back while someone else has it
+	other, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann 2", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	_, err = userService.RestoreUserService(ctx, ann.ID, admin)
+	assert.ErrorIs(t, err, services.ErrConflict)
+	require.NoError(t, userService.DeleteUserService(ctx, other.ID, admin))
+
+	restored, err := userService.RestoreUserService(ctx, ann.ID, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", restored.Email)
+	_, err = userService.RestoreUserService(ctx, ann.ID, admin)
+	assert.ErrorIs(t, err, services.ErrDeletedUserNotFound)
+
+	page, err := userService.ListDeletedUsersService(ctx, dto.DeletedUserQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, other.ID, page.Users[0].ID)
+
+	// Within retention nothing is purged, past it the user is anonymized
+	purged, err := services.NewUserPurgeService(repo, audit, config.UserPurgeConfig{Retention: 24 * time.Hour, Mode: config.UserPurgeAnonymize}).PurgeService(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = services.NewUserPurgeService(repo, audit, config.UserPurgeConfig{Retention: -time.Hour, Mode: config.UserPurgeAnonymize}).PurgeService(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	page, err = userService.ListDeletedUsersService(ctx, dto.DeletedUserQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+	_, err = userService.RestoreUserService(ctx, other.ID, admin)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+	assert.Contains(t, auditRepo.types(), services.EventUserRestored)
+	assert.Equal(t, services.EventUserPurged, auditRepo.types()[len(auditRepo.types())-1])
+}
+
+// TestDeleteUserRevokesSessionsAndAPIKeys checks that a deleted user's tokens stop working, also
+// once restored
+func TestDeleteUserRevokesSessionsAndAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	apiKeys := newTestAPIKeyService(repo)
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), newTestEmailService(repo, audit), apiKeys)
+
+	ann, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	_, token, err := userService.LoginUserService(ctx, dto.LoginRequest{Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token)
+	require.NoError(t, err)
+	key, err := apiKeys.CreateAPIKeyService(ctx, ann.ID, dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"write"}})
+	require.NoError(t, err)
+
+	require.NoError(t, userService.DeleteUserService(ctx, ann.ID, dto.Actor{ID: ann.ID}))
+	_, err = userService.RestoreUserService(ctx, ann.ID, dto.Actor{ID: 99})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, sessions.ValidateSessionService(ctx, claims.SessionID), services.ErrSessionTerminated)
+	_, _, err = apiKeys.AuthenticateAPIKeyService(ctx, key.Key, "192.0.2.10")
+	assert.ErrorIs(t, err, services.ErrAPIKeyRevoked)
+}
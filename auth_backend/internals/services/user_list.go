@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/internals/repositories"
+)
+
+// defaultUserPageSize is the page size of GET /users without a limit
+const defaultUserPageSize = 50
+
+// ErrInvalidCursor is returned for a cursor that wasn't issued for the requested sort order
+var ErrInvalidCursor = NewError(ErrValidation, "invalid_cursor", "invalid cursor, start again from the first page")
+
+// userCursor is the position after the last user of a page. It is sent to clients base64
+// encoded, they should treat it as opaque.
+type userCursor struct {
+	Sort  string `json:"s"`  // sort of the page the cursor was issued for, e.g. "-created_at"
+	Value string `json:"v"`  // sort value of the last user, formatted by cursorValue
+	ID    uint   `json:"id"` // ID of the last user, breaks ties
+}
+
+// ListUsersService returns one page of users matching the query. The page is chosen by offset
+// or, more efficiently for deep pages, by the cursor of the previous page.
+func (s *userServiceImpl) ListUsersService(ctx context.Context, query dto.UserListQuery) (*dto.UserPage, error) {
+	// Step 1: Sort and paging
+	sort := query.Sort
+	if sort == "" {
+		sort = "id"
+	}
+	sortBy, desc := strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultUserPageSize
+	}
+
+	var after *repositories.UserKey
+	if query.Cursor != "" {
+		if query.Offset != 0 {
+			err := NewError(ErrValidation, "invalid_request", "The request has invalid fields")
+			err.Fields = []dto.FieldError{{Field: "cursor", Rule: "excluded_with", Message: "can't be combined with offset"}}
+			return nil, err
+		}
+		key, err := decodeUserCursor(query.Cursor, sort, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		after = key
+	}
+
+	// Step 2: One row more than asked for tells whether there is a next page
+	users, total, err := s.userRepo.ListUsers(ctx, repositories.UserListQuery{
+		Filter: repositories.UserFilter{
+			Role:        query.Role,
+			MinAge:      query.MinAge,
+			MaxAge:      query.MaxAge,
+			CreatedFrom: query.CreatedFrom,
+			CreatedTo:   query.CreatedTo,
+			Email:       query.Email,
+			Name:        query.Name,
+		},
+		SortBy: sortBy,
+		Desc:   desc,
+		Limit:  limit + 1,
+		Offset: query.Offset,
+		After:  after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Map to DTOs, with the cursor of the last one when there is more
+	page := &dto.UserPage{Users: make([]dto.UserResponse, 0, min(len(users), limit)), Total: total, Limit: limit, Offset: query.Offset}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextCursor = encodeUserCursor(userCursor{Sort: sort, Value: cursorValue(users[len(users)-1], sortBy), ID: users[len(users)-1].ID})
+	}
+	for i := range users {
+		page.Users = append(page.Users, newUserResponse(&users[i]))
+	}
+	return page, nil
+}
+
+// cursorValue formats the sort column of a user for the cursor
+func cursorValue(user models.User, sortBy string) string {
+	switch sortBy {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "age":
+		return strconv.Itoa(user.Age)
+	case "created_at":
+		return user.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+func encodeUserCursor(cursor userCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeUserCursor checks a cursor against the requested sort and returns the key to continue after
+func decodeUserCursor(raw, sort, sortBy string) (*repositories.UserKey, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	key := &repositories.UserKey{ID: cursor.ID, Value: cursor.Value}
+	switch sortBy {
+	case "age":
+		age, err := strconv.Atoi(cursor.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		key.Value = age
+	case "created_at":
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		key.Value = createdAt
+	case "id":
+		key.Value = cursor.ID
+	}
+	return key, nil
+}
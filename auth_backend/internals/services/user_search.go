@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+)
+
+// defaultSearchLimit is the number of results of GET /users/search without a limit
+const defaultSearchLimit = 20
+
+// SearchUsersService finds users by partial name or email, best match first, with the matched
+// words highlighted
+func (s *userServiceImpl) SearchUsersService(ctx context.Context, query dto.UserSearchQuery) (*dto.UserSearchResult, error) {
+	// Step 1: The query needs at least one word to match on
+	terms := repositories.SearchTerms(query.Q)
+	if len(terms) == 0 {
+		err := NewError(ErrValidation, "invalid_request", "The request has invalid fields")
+		err.Fields = []dto.FieldError{{Field: "q", Rule: "alphanum", Message: "must contain letters or digits"}}
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+
+	// Step 2: Search and map to DTOs, so only the fields of UserResponse are returned
+	matches, err := s.userRepo.SearchUsers(ctx, query.Q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.UserSearchResult{Query: query.Q, Users: make([]dto.UserSearchHit, 0, len(matches))}
+	for i := range matches {
+		user := &matches[i].User
+		result.Users = append(result.Users, dto.UserSearchHit{
+			UserResponse: newUserResponse(user),
+			Rank:         matches[i].Rank,
+			Highlight: dto.UserHighlight{
+				Name:  highlight(user.Name, terms),
+				Email: highlight(user.Email, terms),
+			},
+		})
+	}
+	return result, nil
+}
+
+// highlight HTML escapes text and wraps the parts matching a term (ignoring case) in <mark>
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Step 1: Flag every rune covered by a match
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	// Step 2: Escape runs of flagged and unflagged runes
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			segment = "<mark>" + segment + "</mark>"
+		}
+		b.WriteString(segment)
+		i = j
+	}
+	return b.String()
+}
@@ -1,8 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 
 	"github.com/devesh121/userAuth/internals/dto"          // Request and response DTOs
 	"github.com/devesh121/userAuth/internals/models"       // DB models
@@ -15,35 +19,49 @@ import (
 
 // UserService interface defines business logic layer functions
 type UserService interface {
-	RegisterUserService(userReq dto.RegisterRequest) (*dto.UserResponse, error)
-	LoginUserService(userReq dto.LoginRequest) (*dto.LoginResponse, string, error)
+	RegisterUserService(ctx context.Context, userReq dto.RegisterRequest, client dto.ClientInfo) (*dto.UserResponse, error)
+	LoginUserService(ctx context.Context, userReq dto.LoginRequest, client dto.ClientInfo) (*dto.LoginResponse, string, error)
 	LogoutUserService(c *gin.Context) error
-	GetAllUsersService() ([]dto.UserResponse, error)
-	GetUserByIDService(id uint) (*dto.UserResponse, error)
-	GetUserByEmailService(email string) (*dto.UserResponse, error)
-	UpdateUserService(userReq dto.UpdateRequest, id uint) (*dto.UserResponse, error)
-	DeleteUserService(id uint) error
+	ListUsersService(ctx context.Context, query dto.UserListQuery) (*dto.UserPage, error)
+	SearchUsersService(ctx context.Context, query dto.UserSearchQuery) (*dto.UserSearchResult, error)
+	GetUserByIDService(ctx context.Context, id uint) (*dto.UserResponse, error)
+	GetUserByEmailService(ctx context.Context, email string) (*dto.UserResponse, error)
+	UpdateUserService(ctx context.Context, userReq dto.UpdateRequest, id, version uint, actor dto.Actor) (*dto.UserResponse, error)
+	PatchUserService(ctx context.Context, patch dto.UserPatch, id, version uint, actor dto.Actor) (*dto.UserResponse, error)
+	DeleteUserService(ctx context.Context, id uint, actor dto.Actor) error
+	ListDeletedUsersService(ctx context.Context, query dto.DeletedUserQuery) (*dto.DeletedUserPage, error)
+	RestoreUserService(ctx context.Context, id uint, actor dto.Actor) (*dto.UserResponse, error)
 }
 
 // userServiceImpl struct implements the UserService interface
 type userServiceImpl struct {
-	userRepo repositories.UserRepo // Depends on abstraction of repo layer
+	userRepo       repositories.UserRepo    // Depends on abstraction of repo layer
+	sessions       SessionService           // Login sessions, one per successful login
+	audit          AuditService             // Audit log of account and auth events
+	history        LoginHistoryService      // Per user login history and new device alerts
+	emails         EmailVerificationService // Verification links for new and changed emails
+	apiKeys        APIKeyService            // Personal API keys, revoked with the user
+	authenticators []Authenticator          // Login strategies, tried in order
 }
 
 // NewUserService constructor returns implementation of UserService interface for future use in controller layer.
-func NewUserService(repo repositories.UserRepo) UserService {
-	return &userServiceImpl{userRepo: repo}
+// Without authenticators only the local bcrypt strategy is used.
+func NewUserService(repo repositories.UserRepo, sessions SessionService, audit AuditService, history LoginHistoryService, emails EmailVerificationService, apiKeys APIKeyService, authenticators ...Authenticator) UserService {
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewLocalAuthenticator(repositories.Uncached(repo))} // the cache keeps no password hashes
+	}
+	return &userServiceImpl{userRepo: repo, sessions: sessions, audit: audit, history: history, emails: emails, apiKeys: apiKeys, authenticators: authenticators}
 }
 
 // RegisterUserService handles the business logic of registering a new user
-func (s *userServiceImpl) RegisterUserService(userReq dto.RegisterRequest) (*dto.UserResponse, error) {
+func (s *userServiceImpl) RegisterUserService(ctx context.Context, userReq dto.RegisterRequest, client dto.ClientInfo) (*dto.UserResponse, error) {
 	// Step 1: Check if user already exists by email
-	existingUser, err := s.userRepo.GetUserByEmail(userReq.Email)
+	existingUser, err := s.userRepo.GetUserByEmail(ctx, userReq.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 	if existingUser != nil {
-		return nil, errors.New("user already exists")
+		return nil, ErrEmailTaken
 	}
 
 	// Step 2: Hash the password using bcrypt before saving it to DB
@@ -52,58 +70,78 @@ func (s *userServiceImpl) RegisterUserService(userReq dto.RegisterRequest) (*dto
 		return nil, errors.New("failed to hash password")
 	}
 
-	// Default role to "user" if not provided
-	role := userReq.Role
-	if role == "" {
-		role = "user"
-	}
-
 	// Step 3: Map the request DTO to DB model
 	newUser := &models.User{
 		Name:     userReq.Name,
 		Email:    userReq.Email,
 		Password: string(hashedPassword),
 		Age:      userReq.Age,
-		Role:     role,
+		Role:     "user", // whatever the request says, anyone can sign up
 	}
 
 	// Step 4: Call repo to create the user in DB
-	createdUser, err := s.userRepo.CreateUser(newUser)
+	createdUser, err := s.userRepo.CreateUser(ctx, newUser)
 	if err != nil {
+		// Lost a race with another registration, or the email belongs to a deleted user
+		if errors.Is(err, repositories.ErrDuplicateEmail) {
+			return nil, ErrEmailTaken
+		}
 		return nil, errors.New("failed to create user")
 	}
+	countRegistration("local")
 
-	// Step 5: Return the response DTO (don't include password)
-	response := &dto.UserResponse{
-		ID:    createdUser.ID,
-		Name:  createdUser.Name,
-		Email: createdUser.Email,
-		Age:   createdUser.Age,
-		Role:  createdUser.Role,
-	}
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventUserRegistered,
+		Actor:      dto.Actor{ID: createdUser.ID, Email: createdUser.Email, ClientInfo: client},
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(createdUser.ID), 10),
+		Metadata:   map[string]any{"role": createdUser.Role},
+	})
+
+	// Step 5: Ask the user to confirm the email
+	s.emails.SendVerification(createdUser)
 
-	return response, nil
+	// Step 6: Return the response DTO (don't include password)
+	response := newUserResponse(createdUser)
+	return &response, nil
 }
 
 // LoginUserService handles the business logic of user login
-func (s *userServiceImpl) LoginUserService(userReq dto.LoginRequest) (*dto.LoginResponse, string, error) {
-	//  Find user by email
-	user, err := s.userRepo.GetUserByEmail(userReq.Email)
+func (s *userServiceImpl) LoginUserService(ctx context.Context, userReq dto.LoginRequest, client dto.ClientInfo) (*dto.LoginResponse, string, error) {
+	// Try each login strategy in order, the first one that accepts the credentials wins
+	user, method, err := s.authenticate(ctx, userReq.Email, userReq.Password)
 	if err != nil {
-		return nil, "", errors.New("no such user found")
+		countLogin(method, err)
+		s.audit.Record(ctx, AuditEntry{
+			Type:     EventLoginFailed,
+			Actor:    dto.Actor{ClientInfo: client},
+			Metadata: map[string]any{"email": userReq.Email, "reason": err.Error()},
+		})
+		// Failed attempts on an existing account show up in its login history
+		if known, lookupErr := s.userRepo.GetUserByEmail(ctx, userReq.Email); lookupErr == nil {
+			s.history.RecordLoginService(ctx, LoginAttemptInput{User: known, Client: client, Err: err})
+		}
+		return nil, "", err
 	}
 
-	// Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(userReq.Password))
+	//  Persist the session and generate the JWT bound to it
+	token, err := s.sessions.StartSessionService(ctx, user, client, method)
+	s.history.RecordLoginService(ctx, LoginAttemptInput{User: user, AuthMethod: method, Client: client, Err: err})
+	countLogin(method, err)
 	if err != nil {
-		return nil, "", errors.New("invalid credentials password or email")
+		s.audit.Record(ctx, AuditEntry{
+			Type:     EventLoginFailed,
+			Actor:    dto.Actor{ID: user.ID, Email: user.Email, ClientInfo: client},
+			Metadata: map[string]any{"email": user.Email, "method": method, "reason": err.Error()},
+		})
+		return nil, "", err
 	}
 
-	//  Generate JWT
-	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role)
-	if err != nil {
-		return nil, "", errors.New("failed to generate token")
-	}
+	s.audit.Record(ctx, AuditEntry{
+		Type:     EventLoginSucceeded,
+		Actor:    dto.Actor{ID: user.ID, Email: user.Email, ClientInfo: client},
+		Metadata: map[string]any{"method": method},
+	})
 
 	// Return token + user details
 	return &dto.LoginResponse{
@@ -115,8 +153,56 @@ func (s *userServiceImpl) LoginUserService(userReq dto.LoginRequest) (*dto.Login
 	}, token, nil
 }
 
+// authenticate runs the configured authenticators and reports the most specific failure.
+// It also returns the name of the strategy that accepted the credentials, or that knew the user
+// but refused them ("" when none did).
+func (s *userServiceImpl) authenticate(ctx context.Context, login, password string) (*models.User, string, error) {
+	lastErr, lastMethod := ErrUserNotFound, ""
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, login, password)
+		if err == nil {
+			return user, authenticator.Name(), nil
+		}
+
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			// try the next strategy
+		case errors.Is(err, ErrInvalidCredentials):
+			lastErr, lastMethod = ErrInvalidCredentials, authenticator.Name()
+		case errors.Is(err, ErrAccountNotLinked):
+			// the directory accepted the password but the email is another account's
+			lastErr, lastMethod = ErrAccountNotLinked, authenticator.Name()
+		default:
+			// e.g. directory unreachable: log it and fall through to the next strategy
+			log.Printf("%s authentication error: %v", authenticator.Name(), err)
+		}
+	}
+	return nil, lastMethod, lastErr
+}
+
 // LogoutUserService handles the business logic of user logout
 func (s *userServiceImpl) LogoutUserService(c *gin.Context) error {
+	ctx := c.Request.Context()
+
+	// Terminate the session of the presented token (cookie or bearer), so the token stops working too
+	token, _ := c.Cookie("auth_token")
+	if scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " "); token == "" && ok && strings.EqualFold(scheme, "Bearer") {
+		token = value
+	}
+	if claims, err := utils.ValidateJWT(token); err == nil {
+		if claims.SessionID != "" {
+			if err := s.sessions.RevokeSessionService(ctx, claims.UserID, claims.SessionID); err != nil {
+				return err
+			}
+		}
+		s.audit.Record(ctx, AuditEntry{
+			Type:       EventLogout,
+			Actor:      dto.Actor{ID: claims.UserID, Email: claims.Email, ClientInfo: dto.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}},
+			TargetType: "session",
+			TargetID:   claims.SessionID,
+		})
+	}
+
 	// Try to get the cookie value (auth_token)
 	_, err := c.Cookie("auth_token")
 	if err == nil {
@@ -130,122 +216,203 @@ func (s *userServiceImpl) LogoutUserService(c *gin.Context) error {
 	return nil
 }
 
-// GetAllUsersService retrieves all users and returns them as DTOs
-func (s *userServiceImpl) GetAllUsersService() ([]dto.UserResponse, error) {
-	// Step 1: Call the repository to get all users
-	users, err := s.userRepo.GetAllUsers()
-	if err != nil {
-		return nil, err
-	}
-
-	// Step 2: Map DB models to DTOs
-	var userResponses []dto.UserResponse
-	for _, user := range users {
-		userResponses = append(userResponses, dto.UserResponse{
-			ID:    user.ID,
-			Name:  user.Name,
-			Email: user.Email,
-			Age:   user.Age,
-			Role:  user.Role,
-		})
-	}
-
-	return userResponses, nil
-}
-
 // GetUserByIDService retrieves a user by ID
-func (s *userServiceImpl) GetUserByIDService(id uint) (*dto.UserResponse, error) {
+func (s *userServiceImpl) GetUserByIDService(ctx context.Context, id uint) (*dto.UserResponse, error) {
 	// Step 1: Call the repository to get the user by ID
-	user, err := s.userRepo.GetUserByID(id)
+	user, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, userLookupError(err)
 	}
 
 	// Step 2: Return the user data as a DTO
-	return &dto.UserResponse{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-		Age:   user.Age,
-		Role:  user.Role,
-	}, nil
+	response := newUserResponse(user)
+	return &response, nil
 }
 
 // GetUserByEmailService retrieves a user by email
-func (s *userServiceImpl) GetUserByEmailService(email string) (*dto.UserResponse, error) {
+func (s *userServiceImpl) GetUserByEmailService(ctx context.Context, email string) (*dto.UserResponse, error) {
 	// Call the repository to get the user by email
-	user, err := s.userRepo.GetUserByEmail(email)
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return nil, userLookupError(err)
 	}
 
 	// Return the user data as a DTO
-	return &dto.UserResponse{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-		Age:   user.Age,
-		Role:  user.Role,
-	}, nil
+	response := newUserResponse(user)
+	return &response, nil
 }
 
-// UpdateUserService updates an existing user's details
-func (s *userServiceImpl) UpdateUserService(userReq dto.UpdateRequest, id uint) (*dto.UserResponse, error) {
-	// Step 1: Fetch the existing user from the repository
-	user, err := s.userRepo.GetUserByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Step 2: Update user fields (only update non-empty fields)
+// UpdateUserService updates an existing user's details. Empty fields are kept, so it is a
+// PatchUserService of the non-empty ones.
+func (s *userServiceImpl) UpdateUserService(ctx context.Context, userReq dto.UpdateRequest, id, version uint, actor dto.Actor) (*dto.UserResponse, error) {
+	var patch dto.UserPatch
 	if userReq.Name != "" {
-		user.Name = userReq.Name
+		patch.Name = &userReq.Name
 	}
 	if userReq.Email != "" {
-		user.Email = userReq.Email
+		patch.Email = &userReq.Email
 	}
 	if userReq.Password != "" {
+		patch.Password = &userReq.Password
+	}
+	if userReq.Age != 0 {
+		patch.Age = &userReq.Age
+	}
+	return s.PatchUserService(ctx, patch, id, version, actor)
+}
+
+// PatchUserService changes the fields set in the patch, and only those. version is the one the
+// client read (0: whatever is current); the update fails with ErrVersionMismatch once the user
+// has moved on. A new email stays pending until confirmed, see EmailVerificationService.
+func (s *userServiceImpl) PatchUserService(ctx context.Context, patch dto.UserPatch, id, version uint, actor dto.Actor) (*dto.UserResponse, error) {
+	// Step 1: Fetch the existing user from the repository, it must still be the version the client read
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userLookupError(err)
+	}
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	// Step 2: Apply the patch, remembering what changed for the audit log
+	var changed []string
+	if patch.Name != nil && *patch.Name != user.Name {
+		user.Name = *patch.Name
+		changed = append(changed, "name")
+	}
+	emailRequested := false
+	if patch.Email != nil {
+		switch {
+		case *patch.Email == user.Email:
+			// Back to the current email: drop the change waiting for confirmation
+			if user.PendingEmail != "" {
+				user.PendingEmail = ""
+				changed = append(changed, "pending_email")
+			}
+		default:
+			// The email must be free now, the unique index checks again on confirmation
+			existingUser, err := s.userRepo.GetUserByEmail(ctx, *patch.Email)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if existingUser != nil {
+				return nil, ErrEmailTaken
+			}
+			if *patch.Email != user.PendingEmail {
+				user.PendingEmail = *patch.Email
+				changed = append(changed, "pending_email")
+			}
+			emailRequested = true // asking again sends the links again
+		}
+	}
+	if patch.Password != nil {
 		// Hash the new password before saving
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userReq.Password), bcrypt.DefaultCost)
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*patch.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return nil, errors.New("failed to hash password")
 		}
 		user.Password = string(hashedPassword)
 	}
-	if userReq.Age != 0 {
-		user.Age = userReq.Age
+	if patch.Age != nil && *patch.Age != user.Age {
+		user.Age = *patch.Age
+		changed = append(changed, "age")
 	}
 
 	// Step 3: Call the repository to save the updated user
-	updatedUser, err := s.userRepo.UpdateUser(user)
+	updatedUser, err := s.userRepo.UpdateUser(ctx, user)
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, repositories.ErrDuplicateEmail):
+			return nil, ErrEmailTaken
+		case errors.Is(err, repositories.ErrStaleUser):
+			return nil, ErrVersionMismatch.Wrap(err) // updated by someone else in between
+		}
+		return nil, userLookupError(err)
 	}
 
-	// Step 4: Return the updated user as a DTO
-	return &dto.UserResponse{
-		ID:    updatedUser.ID,
-		Name:  updatedUser.Name,
-		Email: updatedUser.Email,
-		Age:   updatedUser.Age,
-	}, nil
+	target := strconv.FormatUint(uint64(updatedUser.ID), 10)
+	if len(changed) > 0 {
+		s.audit.Record(ctx, AuditEntry{
+			Type:       EventUserUpdated,
+			Actor:      actor,
+			TargetType: "user",
+			TargetID:   target,
+			Metadata:   map[string]any{"fields": changed},
+		})
+	}
+	if patch.Password != nil {
+		s.audit.Record(ctx, AuditEntry{Type: EventPasswordChanged, Actor: actor, TargetType: "user", TargetID: target})
+	}
+
+	// Step 4: The new email is only used once its owner opens the link, the old one can revert
+	if emailRequested {
+		s.emails.SendEmailChange(updatedUser)
+	}
+
+	// Step 5: Return the updated user as a DTO
+	response := newUserResponse(updatedUser)
+	return &response, nil
 }
 
 // DeleteUserService deletes a user by ID
-func (s *userServiceImpl) DeleteUserService(id uint) error {
-	// Check if user exists
-	_, err := s.userRepo.GetUserByID(id)
+func (s *userServiceImpl) DeleteUserService(ctx context.Context, id uint, actor dto.Actor) error {
+	// Step 1: Check if user exists
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return userLookupError(err)
+	}
+
+	// Step 2: Sign the user out and revoke the API keys first, so no token outlives the account
+	// and a failed revocation can be retried
+	sessions, err := s.sessions.RevokeOtherSessionsService(ctx, id, "")
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
-		}
 		return err
 	}
-	// Proceed to delete
-	err = s.userRepo.DeleteUser(id)
+	apiKeys, err := s.apiKeys.RevokeUserAPIKeysService(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	// Step 3: Proceed to delete
+	err = s.userRepo.DeleteUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Keep who the user was, the row itself is gone
+	s.audit.Record(ctx, AuditEntry{
+		Type:       EventUserDeleted,
+		Actor:      actor,
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(id), 10),
+		Metadata: map[string]any{
+			"email": user.Email, "name": user.Name, "role": user.Role,
+			"sessions_revoked": sessions, "api_keys_revoked": apiKeys,
+		},
+	})
+
 	return nil
 }
+
+// userLookupError turns a missing row into ErrUserNotExist and passes other errors through
+func userLookupError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotExist.Wrap(err)
+	}
+	return err
+}
+
+// newUserResponse maps a user to its response DTO, without the password
+func newUserResponse(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		PendingEmail:  user.PendingEmail,
+		Age:           user.Age,
+		Role:          user.Role,
+		CreatedAt:     user.CreatedAt,
+		Version:       user.Version,
+	}
+}
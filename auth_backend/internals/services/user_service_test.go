@@ -1 +1,175 @@
 package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/cache"
+	"github.com/devesh121/userAuth/internals/dto"
+	"github.com/devesh121/userAuth/internals/repositories"
+	"github.com/devesh121/userAuth/internals/services"
+	"github.com/devesh121/userAuth/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestUserService returns a user service with in-memory stores and the audit repo it writes to
+func newTestUserService() (services.UserService, *fakeAuditRepo) {
+	auditRepo := &fakeAuditRepo{}
+	audit := services.NewAuditService(auditRepo, config.AuditConfig{})
+	repo := repositories.NewMemoryUserRepo()
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	return services.NewUserService(repo, sessions, audit, newTestLoginHistory(), newTestEmailService(repo, audit), newTestAPIKeyService(repo)), auditRepo
+}
+
+// newTestEmailService returns an email verification service whose mails go nowhere
+func newTestEmailService(repo repositories.UserRepo, audit services.AuditService) services.EmailVerificationService {
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	return services.NewEmailVerificationService(repo, sessions, audit, &fakeMailer{}, "http://localhost:8080")
+}
+
+// TestDefaultAuthenticatorBehindCache checks that the fallback local strategy checks passwords
+// against the store when the user repository is cached, as the cache keeps no password hashes
+func TestDefaultAuthenticatorBehindCache(t *testing.T) {
+	ctx := context.Background()
+	audit := services.NewAuditService(&fakeAuditRepo{}, config.AuditConfig{})
+	repo := repositories.NewCachedUserRepo(repositories.NewMemoryUserRepo(), cache.NewLRU(10), time.Minute)
+	sessions := services.NewSessionService(newFakeSessionRepo(), config.SessionLimitConfig{}, audit)
+	userService := services.NewUserService(repo, sessions, audit, newTestLoginHistory(), newTestEmailService(repo, audit), newTestAPIKeyService(repo))
+
+	_, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	for range 2 { // the second login finds Ann in the cache
+		_, _, err = userService.LoginUserService(ctx, dto.LoginRequest{Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+		require.NoError(t, err)
+	}
+}
+
+// TestUserServiceWritesAuditEvents checks the events of a register, login, update and delete
+func TestUserServiceWritesAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	userService, auditRepo := newTestUserService()
+	client := dto.ClientInfo{IP: "192.0.2.10", UserAgent: "test"}
+
+	user, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass", Age: 30, Role: "admin"}, client)
+	require.NoError(t, err)
+	assert.Equal(t, "user", user.Role, "a sign-up never picks its role")
+
+	_, _, err = userService.LoginUserService(ctx, dto.LoginRequest{Email: "ann@example.com", Password: "nope"}, client)
+	assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	_, _, err = userService.LoginUserService(ctx, dto.LoginRequest{Email: "ann@example.com", Password: "secret-pass"}, client)
+	require.NoError(t, err)
+
+	admin := dto.Actor{ID: 99, Email: "admin@example.com", ClientInfo: client}
+	_, err = userService.UpdateUserService(ctx, dto.UpdateRequest{Name: "Ann B", Email: "ann@example.com", Age: 30, Password: "new-pass"}, user.ID, 0, admin)
+	require.NoError(t, err)
+	require.NoError(t, userService.DeleteUserService(ctx, user.ID, admin))
+
+	assert.Equal(t, []string{
+		services.EventUserRegistered,
+		services.EventLoginFailed,
+		services.EventLoginSucceeded,
+		services.EventUserUpdated,
+		services.EventPasswordChanged,
+		services.EventUserDeleted,
+	}, auditRepo.types())
+
+	// The deleted user is still identifiable from the log
+	page, err := services.NewAuditService(auditRepo, config.AuditConfig{}).QueryEventsService(ctx, dto.AuditQuery{EventType: services.EventUserDeleted})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, uint(99), *page.Events[0].ActorID)
+	assert.Equal(t, "ann@example.com", page.Events[0].Metadata["email"])
+	assert.Equal(t, []string{"name"}, auditRepo.events[3].Metadata["fields"])
+}
+
+// TestUserServiceTypedErrors checks the kinds of the errors handlers map to 404 and 409
+func TestUserServiceTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	userService, _ := newTestUserService()
+
+	_, err := userService.GetUserByIDService(ctx, 42)
+	assert.ErrorIs(t, err, services.ErrUserNotExist)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+	assert.ErrorIs(t, userService.DeleteUserService(ctx, 42, dto.Actor{}), services.ErrNotFound)
+
+	ann, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+	_, err = userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Bob", Email: "bob@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	require.NoError(t, err)
+
+	_, err = userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "secret-pass"}, dto.ClientInfo{})
+	assert.ErrorIs(t, err, services.ErrEmailTaken)
+	_, err = userService.UpdateUserService(ctx, dto.UpdateRequest{Email: "bob@example.com"}, ann.ID, 0, dto.Actor{})
+	assert.ErrorIs(t, err, services.ErrConflict)
+
+	// Updates carry the version they were made from
+	_, err = userService.UpdateUserService(ctx, dto.UpdateRequest{Name: "Ann B"}, ann.ID, ann.Version+1, dto.Actor{})
+	assert.ErrorIs(t, err, services.ErrVersionMismatch)
+	assert.ErrorIs(t, err, services.ErrPreconditionFailed)
+	updated, err := userService.UpdateUserService(ctx, dto.UpdateRequest{Name: "Ann B"}, ann.ID, ann.Version, dto.Actor{})
+	require.NoError(t, err)
+	assert.Equal(t, ann.Version+1, updated.Version)
+}
+
+// TestListUsersCursorPaging walks all pages through next_cursor and checks cursor validation
+func TestListUsersCursorPaging(t *testing.T) {
+	ctx := context.Background()
+	userService, _ := newTestUserService()
+	for _, name := range []string{"Eve", "Ann", "Dan", "Bob", "Cat"} {
+		_, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: name, Email: name + "@example.com", Password: "secret-pass", Age: 30}, dto.ClientInfo{})
+		require.NoError(t, err)
+	}
+
+	var names []string
+	query := dto.UserListQuery{Sort: "-name", Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+		page, err := userService.ListUsersService(ctx, query)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, page.Total)
+		for _, user := range page.Users {
+			names = append(names, user.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Eve", "Dan", "Cat", "Bob", "Ann"}, names)
+
+	// A cursor only fits the sort it was issued for, and not together with offset
+	first, err := userService.ListUsersService(ctx, dto.UserListQuery{Sort: "-name", Limit: 2})
+	require.NoError(t, err)
+	_, err = userService.ListUsersService(ctx, dto.UserListQuery{Sort: "age", Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+	_, err = userService.ListUsersService(ctx, dto.UserListQuery{Sort: "-name", Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+	_, err = userService.ListUsersService(ctx, dto.UserListQuery{Sort: "-name", Cursor: first.NextCursor, Offset: 2})
+	assert.ErrorIs(t, err, services.ErrValidation)
+}
+
+// TestSearchUsersHighlights checks that hits carry escaped, highlighted fields and no password
+func TestSearchUsersHighlights(t *testing.T) {
+	ctx := context.Background()
+	userService, _ := newTestUserService()
+	_, err := userService.RegisterUserService(ctx, dto.RegisterRequest{Name: "Ann <b>Lee</b>", Email: "ann.lee@example.com", Password: "secret-pass", Age: 30}, dto.ClientInfo{})
+	require.NoError(t, err)
+
+	result, err := userService.SearchUsersService(ctx, dto.UserSearchQuery{Q: "LEE ann"})
+	require.NoError(t, err)
+	require.Len(t, result.Users, 1)
+	hit := result.Users[0]
+	assert.Equal(t, "<mark>Ann</mark> &lt;b&gt;<mark>Lee</mark>&lt;/b&gt;", hit.Highlight.Name)
+	assert.Equal(t, "<mark>ann</mark>.<mark>lee</mark>@example.com", hit.Highlight.Email)
+	assert.Positive(t, hit.Rank)
+
+	body, err := json.Marshal(hit)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "password")
+
+	_, err = userService.SearchUsersService(ctx, dto.UserSearchQuery{Q: "@@"})
+	assert.ErrorIs(t, err, services.ErrValidation)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purposes of action tokens, the links sent by email
+const (
+	PurposeVerifyEmail  = "verify_email"  // Email is the address to mark verified
+	PurposeConfirmEmail = "confirm_email" // Email is the pending address to switch to
+	PurposeRevertEmail  = "revert_email"  // Email is the address to go back to, NewEmail the one being changed to
+)
+
+// actionSecret signs action tokens. It is derived from JWT_SECRET but differs from it, so an
+// emailed link is never accepted as a login token and a login token never as a link.
+var actionSecret = func() []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("action tokens"))
+	return mac.Sum(nil)
+}()
+
+// ActionClaims are the claims of an action token: what it allows, for which user and email
+type ActionClaims struct {
+	Purpose  string `json:"purpose"`
+	UserID   uint   `json:"user_id"`
+	Email    string `json:"email"`
+	NewEmail string `json:"new_email,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken returns a signed token allowing purpose for the user and email until ttl
+// passes. newEmail is only used by PurposeRevertEmail.
+func GenerateActionToken(purpose string, userID uint, email, newEmail string, ttl time.Duration) (string, error) {
+	claims := ActionClaims{
+		Purpose:  purpose,
+		UserID:   userID,
+		Email:    email,
+		NewEmail: newEmail,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(actionSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign action token: %w", err)
+	}
+	return token, nil
+}
+
+// ValidateActionToken checks signature, expiry and purpose of an action token
+func ValidateActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return actionSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.Purpose != purpose {
+		return nil, errors.New("invalid action token")
+	}
+	return claims, nil
+}
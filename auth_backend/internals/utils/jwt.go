@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,23 +14,37 @@ import (
 
 var jwtSecret = []byte(os.Getenv("JWT_SECRET")) // Make sure to set this in env
 
+// TokenTTL is how long an issued token (and its login session) is valid
+const TokenTTL = 24 * time.Hour
+
 // CustomClaims defines your own claims structure
 type CustomClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Scope     string `json:"scope,omitempty"` // space separated scopes (RFC 8693 style)
+	SessionID string `json:"sid,omitempty"`   // login session the token belongs to
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a JWT token for a user
-func GenerateJWT(userID uint, email, role string) (string, error) {
+// GenerateJWT creates a JWT token for a user's login session
+func GenerateJWT(userID uint, email, role, sessionID string) (string, error) {
+	// Every token gets a unique ID (jti) so it can be revoked later
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
 	// Define custom claims
 	claims := CustomClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        tokenID,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -59,3 +76,12 @@ func ValidateJWT(tokenString string) (*CustomClaims, error) {
 
 	return claims, nil
 }
+
+// newTokenID returns a random 128 bit hex string used as the jti claim
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/devesh121/userAuth/internals/utils"
+	"gorm.io/gorm"
+)
+
+// gormStartKey is the statement setting holding the start time of a query
+const gormStartKey = "metrics:start"
+
+// GormPlugin counts every query in DatabaseOperationsTotal, times it in DatabaseQueryDuration,
+// and logs the ones slower than SlowThreshold with the ID of their request
+type GormPlugin struct {
+	SlowThreshold time.Duration // 0: no slow query log
+}
+
+// NewGormPlugin returns the plugin, to install with db.Use
+func NewGormPlugin(slowThreshold time.Duration) *GormPlugin {
+	return &GormPlugin{SlowThreshold: slowThreshold}
+}
+
+// Name implements gorm.Plugin
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin, wrapping the main callback of every operation
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", p.start),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", p.finish("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", p.start),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", p.finish("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", p.start),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", p.finish("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", p.start),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", p.finish("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", p.start),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", p.finish("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", p.start),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", p.finish("raw")),
+	)
+}
+
+// start remembers when the query began
+func (p *GormPlugin) start(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+// finish records the query of an operation once it ran
+func (p *GormPlugin) finish(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		start, _ := value.(time.Time)
+		if !ok || start.IsZero() {
+			return
+		}
+		elapsed := time.Since(start)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown" // raw SQL
+		}
+		status := "ok"
+		switch {
+		case errors.Is(db.Error, gorm.ErrRecordNotFound):
+			status = "not_found"
+		case db.Error != nil:
+			status = "error"
+		}
+		DatabaseOperationsTotal.WithLabelValues(operation, table, status).Inc()
+		DatabaseQueryDuration.WithLabelValues(operation, table).Observe(elapsed.Seconds())
+
+		// The SQL is logged without its values, they may be personal data or password hashes
+		if p.SlowThreshold > 0 && elapsed >= p.SlowThreshold {
+			requestID := "-"
+			if db.Statement.Context != nil {
+				if id := utils.RequestID(db.Statement.Context); id != "" {
+					requestID = id
+				}
+			}
+			log.Printf("🐢 slow query request_id=%s duration=%s operation=%s table=%s rows=%d: %s",
+				requestID, elapsed.Round(time.Millisecond), operation, table, db.RowsAffected, db.Statement.SQL.String())
+		}
+	}
+}
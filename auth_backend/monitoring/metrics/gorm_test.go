@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/devesh121/userAuth/internals/utils"
+	"github.com/devesh121/userAuth/monitoring/metrics"
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// widget is the table of the test database
+type widget struct {
+	ID   uint
+	Name string
+}
+
+// TestGormPlugin checks the counters and latency of each operation, and the slow query log
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.Exec("CREATE TABLE widgets (id integer PRIMARY KEY, name text)").Error)
+
+	// Every query is slow, so they are all logged
+	require.NoError(t, db.Use(metrics.NewGormPlugin(1)))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	count := func(operation, status string) float64 {
+		return testutil.ToFloat64(metrics.DatabaseOperationsTotal.WithLabelValues(operation, "widgets", status))
+	}
+	created, found, missing := count("create", "ok"), count("query", "ok"), count("query", "not_found")
+	failed := testutil.ToFloat64(metrics.DatabaseOperationsTotal.WithLabelValues("raw", "unknown", "error"))
+
+	ctx := utils.WithRequestID(context.Background(), "req-42")
+	require.NoError(t, db.WithContext(ctx).Create(&widget{Name: "secret value"}).Error)
+	var w widget
+	require.NoError(t, db.First(&w, "name = ?", "secret value").Error)
+	assert.ErrorIs(t, db.First(&w, "name = ?", "nothing").Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Exec("SELECT * FROM no_such_table").Error)
+
+	assert.Equal(t, created+1, count("create", "ok"))
+	assert.Equal(t, found+1, count("query", "ok"))
+	assert.Equal(t, missing+1, count("query", "not_found"))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.DatabaseOperationsTotal.WithLabelValues("raw", "unknown", "error")))
+	assert.Positive(t, testutil.CollectAndCount(metrics.DatabaseQueryDuration))
+
+	assert.Contains(t, logs.String(), "slow query request_id=req-42")
+	assert.Contains(t, logs.String(), "operation=create table=widgets")
+	assert.Contains(t, logs.String(), "request_id=-", "queries outside a request")
+	assert.NotContains(t, logs.String(), "secret value", "values aren't logged")
+}
@@ -27,21 +27,76 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
-	// DatabaseOperationsTotal tracks database operations
+	// DatabaseOperationsTotal tracks database operations, see GormPlugin
 	DatabaseOperationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "database_operations_total",
-			Help: "Total number of database operations",
+			Help: "Total number of database operations by operation, table and status (ok, not_found or error)",
 		},
-		[]string{"operation", "status"},
+		[]string{"operation", "table", "status"},
 	)
 
-	// ActiveUsers tracks current active users
+	// DatabaseQueryDuration tracks database query latency, see GormPlugin
+	DatabaseQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "database_query_duration_seconds",
+			Help:    "Duration of database queries by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"operation", "table"},
+	)
+
+	// ActiveUsers tracks current active users, refreshed from the sessions table
 	ActiveUsers = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "active_users",
-			Help: "Number of currently active users",
+			Help: "Number of users with at least one unexpired, not revoked session",
+		},
+	)
+
+	// RegistrationsTotal tracks new accounts, by registration or first login with an external identity
+	RegistrationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_registrations_total",
+			Help: "New user accounts by auth method (local, ldap, saml or the federation provider)",
+		},
+		[]string{"auth_method"},
+	)
+
+	// LoginsTotal tracks login attempts
+	LoginsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Login attempts by auth method and outcome (success, bad_password, unknown_user, session_limit or failed)",
+		},
+		[]string{"auth_method", "outcome"},
+	)
+
+	// TokenRevocationsTotal tracks tokens that stopped working before their expiry
+	TokenRevocationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_token_revocations_total",
+			Help: "Revoked sessions and tokens by auth method and reason (user, session_limit or oauth)",
+		},
+		[]string{"auth_method", "reason"},
+	)
+
+	// SessionLimitEnforcementsTotal tracks logins that hit the concurrent session limit
+	SessionLimitEnforcementsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "session_limit_enforcements_total",
+			Help: "Logins over the concurrent session limit by role and action (evicted or rejected)",
+		},
+		[]string{"role", "action"},
+	)
+
+	// UserCacheRequestsTotal tracks user lookups answered by the cache
+	UserCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_cache_requests_total",
+			Help: "User cache lookups by key (id or email) and result (hit, miss or error)",
 		},
+		[]string{"lookup", "result"},
 	)
 
 	// RequestsFailed tracks failed requests
@@ -87,7 +142,13 @@ func Initialize() {
 	prometheus.MustRegister(HTTPRequestsTotal)
 	prometheus.MustRegister(HTTPRequestDuration)
 	prometheus.MustRegister(DatabaseOperationsTotal)
+	prometheus.MustRegister(DatabaseQueryDuration)
 	prometheus.MustRegister(ActiveUsers)
+	prometheus.MustRegister(RegistrationsTotal)
+	prometheus.MustRegister(LoginsTotal)
+	prometheus.MustRegister(TokenRevocationsTotal)
+	prometheus.MustRegister(SessionLimitEnforcementsTotal)
+	prometheus.MustRegister(UserCacheRequestsTotal)
 	prometheus.MustRegister(RequestsFailed)
 	prometheus.MustRegister(RequestsInFlight)
 	prometheus.MustRegister(ResponseSize)
@@ -2,21 +2,28 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // DBConfig struct holds all necessary database environment variables
 type DBConfig struct {
-	Host     string // Database host, e.g., localhost
-	Port     string // Port where PostgreSQL is running (default: 5432)
-	User     string // Username for PostgreSQL
-	Password string // Password for PostgreSQL
-	DB_Name  string // Database name (was DB_Name)
-	SSLMode  string // SSL mode setting, e.g., disable
+	Driver     string // "postgres" (default) or "sqlite"
+	Host       string // Database host, e.g., localhost
+	Port       string // Port where PostgreSQL is running (default: 5432)
+	User       string // Username for PostgreSQL
+	Password   string // Password for PostgreSQL
+	DB_Name    string // Database name (was DB_Name)
+	SSLMode    string // SSL mode setting, e.g., disable
+	SQLitePath string // sqlite: database file, ":memory:" for a throwaway database
 }
 
 // LoadEnv loads the .env file into the application
@@ -27,8 +34,21 @@ func LoadEnv() {
 	}
 }
 
-// GetDBConfig returns a populated DBConfig struct using values from the environment
+// GetDBConfig returns a populated DBConfig struct using values from the environment.
+// DB_DRIVER=sqlite runs on a local SQLite file (SQLITE_PATH) and needs no PostgreSQL settings.
 func GetDBConfig() (DBConfig, error) {
+	switch driver := strings.ToLower(os.Getenv("DB_DRIVER")); driver {
+	case "", "postgres":
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "authdb.sqlite"
+		}
+		return DBConfig{Driver: driver, SQLitePath: path}, nil
+	default:
+		return DBConfig{}, fmt.Errorf("unknown DB_DRIVER %q (use postgres or sqlite)", driver)
+	}
+
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
 	user := os.Getenv("DB_USER")
@@ -41,6 +61,7 @@ func GetDBConfig() (DBConfig, error) {
 	}
 
 	return DBConfig{
+		Driver:   "postgres",
 		Host:     host,
 		Port:     port,
 		User:     user,
@@ -50,3 +71,462 @@ func GetDBConfig() (DBConfig, error) {
 	}, nil
 
 }
+
+// Throwaway reports whether the database is lost when the process exits, like a SQLite database
+// in memory
+func (c DBConfig) Throwaway() bool {
+	return c.Driver == "sqlite" && (c.SQLitePath == ":memory:" || strings.HasPrefix(c.SQLitePath, "file::memory:") || strings.Contains(c.SQLitePath, "mode=memory"))
+}
+
+// GetUserStore returns where user accounts are kept: "memory" when USER_STORE=memory (lost on
+// restart, for local development and tests), otherwise the database of DB_DRIVER
+func GetUserStore() string {
+	if strings.EqualFold(os.Getenv("USER_STORE"), "memory") {
+		return "memory"
+	}
+	if strings.EqualFold(os.Getenv("DB_DRIVER"), "sqlite") {
+		return "sqlite"
+	}
+	return "postgres"
+}
+
+// GetOAuthClients returns the client credentials allowed to call /oauth/introspect and /oauth/revoke.
+// OAUTH_CLIENTS is a comma separated list of client_id:client_secret pairs.
+func GetOAuthClients() map[string]string {
+	clients := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("OAUTH_CLIENTS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+		clients[id] = secret
+	}
+	return clients
+}
+
+// FederationProviderConfig holds the settings of one upstream identity provider
+type FederationProviderConfig struct {
+	Name         string   // provider name used in the URL, e.g. "google"
+	Type         string   // "oidc" or "oauth2"
+	IssuerURL    string   // oidc: issuer used for discovery
+	ClientID     string   // client registered at the provider
+	ClientSecret string   // secret of that client
+	RedirectURL  string   // our callback URL, e.g. http://localhost:8080/api/v1/auth/google/callback
+	Scopes       []string // optional, defaults to "openid email profile" for oidc
+	AuthURL      string   // oauth2: authorization endpoint
+	TokenURL     string   // oauth2: token endpoint
+	UserInfoURL  string   // oauth2: endpoint returning the user as JSON
+	TrustEmail   bool     // oauth2: treat the returned email as verified
+}
+
+// GetFederationProviders reads the providers listed in FEDERATION_PROVIDERS.
+// Each provider is configured with FEDERATION_<NAME>_* variables, e.g. FEDERATION_GOOGLE_CLIENT_ID.
+func GetFederationProviders() []FederationProviderConfig {
+	var providers []FederationProviderConfig
+	for _, name := range strings.Split(os.Getenv("FEDERATION_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "FEDERATION_" + strings.ToUpper(name) + "_"
+		cfg := FederationProviderConfig{
+			Name:         name,
+			Type:         os.Getenv(prefix + "TYPE"),
+			IssuerURL:    os.Getenv(prefix + "ISSUER_URL"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			AuthURL:      os.Getenv(prefix + "AUTH_URL"),
+			TokenURL:     os.Getenv(prefix + "TOKEN_URL"),
+			UserInfoURL:  os.Getenv(prefix + "USERINFO_URL"),
+			TrustEmail:   os.Getenv(prefix+"TRUST_EMAIL") == "true",
+		}
+		if cfg.Type == "" {
+			cfg.Type = "oidc"
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			cfg.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+		providers = append(providers, cfg)
+	}
+	return providers
+}
+
+// GetAuthStrategies returns the login strategies tried by LoginUserService, in order.
+// AUTH_STRATEGIES is a comma separated list of "local" and "ldap" (default: local).
+func GetAuthStrategies() []string {
+	var strategies []string
+	for _, s := range strings.Split(os.Getenv("AUTH_STRATEGIES"), ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			strategies = append(strategies, s)
+		}
+	}
+	if len(strategies) == 0 {
+		strategies = []string{"local"}
+	}
+	return strategies
+}
+
+// GroupRole maps a directory / IdP group to an application role
+type GroupRole struct {
+	Group string // group DN for LDAP, group name / value for SAML
+	Role  string
+}
+
+// LDAPConfig holds the settings of the LDAP / Active Directory login strategy
+type LDAPConfig struct {
+	URL            string        // ldap://host:389 or ldaps://host:636
+	StartTLS       bool          // upgrade ldap:// connections with StartTLS
+	BindDN         string        // service account used to search for users
+	BindPassword   string        // password of the service account
+	BaseDN         string        // where users are searched
+	UserFilter     string        // e.g. (mail=%s) or (sAMAccountName=%s) for AD
+	EmailAttribute string        // default: mail
+	NameAttribute  string        // default: cn
+	GroupAttribute string        // default: memberOf
+	GroupRoles     []GroupRole   // first matching group wins
+	DefaultRole    string        // role when no group matches (default: user)
+	Timeout        time.Duration // bound of the connection and each request (0: DefaultLDAPTimeout)
+}
+
+// DefaultLDAPTimeout bounds LDAP requests when LDAP_TIMEOUT is unset
+const DefaultLDAPTimeout = 5 * time.Second
+
+// GetLDAPConfig reads the LDAP_* environment variables.
+// LDAP_GROUP_ROLES is a ";" separated list of group_dn:role pairs, LDAP_TIMEOUT a duration.
+func GetLDAPConfig() LDAPConfig {
+	cfg := LDAPConfig{
+		URL:            os.Getenv("LDAP_URL"),
+		StartTLS:       os.Getenv("LDAP_START_TLS") == "true",
+		BindDN:         os.Getenv("LDAP_BIND_DN"),
+		BindPassword:   os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:         os.Getenv("LDAP_BASE_DN"),
+		UserFilter:     getEnvDefault("LDAP_USER_FILTER", "(mail=%s)"),
+		EmailAttribute: getEnvDefault("LDAP_EMAIL_ATTR", "mail"),
+		NameAttribute:  getEnvDefault("LDAP_NAME_ATTR", "cn"),
+		GroupAttribute: getEnvDefault("LDAP_GROUP_ATTR", "memberOf"),
+		DefaultRole:    getEnvDefault("LDAP_DEFAULT_ROLE", "user"),
+	}
+
+	cfg.GroupRoles = parseGroupRoles(os.Getenv("LDAP_GROUP_ROLES"))
+	if value := os.Getenv("LDAP_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			cfg.Timeout = parsed
+		} else {
+			log.Printf("invalid LDAP_TIMEOUT %q, using %s", value, DefaultLDAPTimeout)
+		}
+	}
+	return cfg
+}
+
+// parseGroupRoles parses a ";" separated list of group:role pairs
+func parseGroupRoles(value string) []GroupRole {
+	var mappings []GroupRole
+	for _, pair := range strings.Split(value, ";") {
+		// group DNs contain "=" and ",", so split on the last ":"
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 || i == len(pair)-1 {
+			continue
+		}
+		mappings = append(mappings, GroupRole{
+			Group: strings.TrimSpace(pair[:i]),
+			Role:  strings.TrimSpace(pair[i+1:]),
+		})
+	}
+	return mappings
+}
+
+// getEnvDefault returns the environment variable or the fallback if it is unset
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// SAMLConfig holds the SAML 2.0 service provider settings
+type SAMLConfig struct {
+	RootURL           string      // public URL of the SAML routes, e.g. http://localhost:8080/api/v1/saml
+	EntityID          string      // optional, defaults to the metadata URL
+	CertFile          string      // SP certificate (PEM)
+	KeyFile           string      // SP private key (PEM, RSA)
+	IDPMetadataURL    string      // IdP metadata, fetched at startup
+	IDPMetadataFile   string      // or read from a local file
+	AllowIDPInitiated bool        // accept responses without a matching AuthnRequest
+	EmailAttribute    string      // attribute name or friendly name, default: email (falls back to the NameID)
+	NameAttribute     string      // default: displayName
+	GroupsAttribute   string      // default: groups
+	GroupRoles        []GroupRole // first matching group wins
+	DefaultRole       string      // role when no group matches (default: user)
+}
+
+// Enabled reports whether enough is configured to start the SAML service provider
+func (c SAMLConfig) Enabled() bool {
+	return c.RootURL != "" && (c.IDPMetadataURL != "" || c.IDPMetadataFile != "")
+}
+
+// GetSAMLConfig reads the SAML_* environment variables.
+// SAML_GROUP_ROLES is a ";" separated list of group:role pairs.
+func GetSAMLConfig() SAMLConfig {
+	return SAMLConfig{
+		RootURL:           strings.TrimSuffix(os.Getenv("SAML_ROOT_URL"), "/"),
+		EntityID:          os.Getenv("SAML_ENTITY_ID"),
+		CertFile:          os.Getenv("SAML_CERT_FILE"),
+		KeyFile:           os.Getenv("SAML_KEY_FILE"),
+		IDPMetadataURL:    os.Getenv("SAML_IDP_METADATA_URL"),
+		IDPMetadataFile:   os.Getenv("SAML_IDP_METADATA_FILE"),
+		AllowIDPInitiated: os.Getenv("SAML_ALLOW_IDP_INITIATED") == "true",
+		EmailAttribute:    getEnvDefault("SAML_ATTR_EMAIL", "email"),
+		NameAttribute:     getEnvDefault("SAML_ATTR_NAME", "displayName"),
+		GroupsAttribute:   getEnvDefault("SAML_ATTR_GROUPS", "groups"),
+		GroupRoles:        parseGroupRoles(os.Getenv("SAML_GROUP_ROLES")),
+		DefaultRole:       getEnvDefault("SAML_DEFAULT_ROLE", "user"),
+	}
+}
+
+// GetTokenLookup returns where JWTAuthMiddleware looks for the token, in order.
+// AUTH_TOKEN_LOOKUP is a comma separated list of "header", "cookie" and "query" (default: header,cookie).
+func GetTokenLookup() []string {
+	var lookup []string
+	for _, source := range strings.Split(getEnvDefault("AUTH_TOKEN_LOOKUP", "header,cookie"), ",") {
+		if source = strings.ToLower(strings.TrimSpace(source)); source != "" {
+			lookup = append(lookup, source)
+		}
+	}
+	return lookup
+}
+
+// Session limit policies applied when a login would exceed the limit
+const (
+	SessionLimitReject      = "reject"       // refuse the new login
+	SessionLimitEvictOldest = "evict_oldest" // sign out the oldest session to make room
+)
+
+// SessionLimitConfig caps the number of simultaneous sessions per account
+type SessionLimitConfig struct {
+	DefaultLimit int            // limit for roles without their own, 0 means unlimited
+	RoleLimits   map[string]int // per role limits, e.g. admin: 1
+	Policy       string         // SessionLimitReject or SessionLimitEvictOldest
+}
+
+// LimitFor returns the session limit of a role, 0 means unlimited
+func (c SessionLimitConfig) LimitFor(role string) int {
+	if limit, ok := c.RoleLimits[role]; ok {
+		return limit
+	}
+	return c.DefaultLimit
+}
+
+// GetSessionLimitConfig reads the SESSION_LIMIT_* environment variables.
+// SESSION_LIMIT_ROLES is a comma separated list of role:limit pairs, e.g. admin:1,user:5.
+func GetSessionLimitConfig() SessionLimitConfig {
+	cfg := SessionLimitConfig{
+		RoleLimits: make(map[string]int),
+		Policy:     getEnvDefault("SESSION_LIMIT_POLICY", SessionLimitEvictOldest),
+	}
+	if limit, err := strconv.Atoi(os.Getenv("SESSION_LIMIT_DEFAULT")); err == nil && limit > 0 {
+		cfg.DefaultLimit = limit
+	}
+	for _, pair := range strings.Split(os.Getenv("SESSION_LIMIT_ROLES"), ",") {
+		role, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || role == "" || err != nil || limit < 0 {
+			continue
+		}
+		cfg.RoleLimits[strings.TrimSpace(role)] = limit
+	}
+	if cfg.Policy != SessionLimitReject && cfg.Policy != SessionLimitEvictOldest {
+		log.Printf("unknown SESSION_LIMIT_POLICY %q, using %s", cfg.Policy, SessionLimitEvictOldest)
+		cfg.Policy = SessionLimitEvictOldest
+	}
+	return cfg
+}
+
+// AuditConfig holds the settings of the audit log checkpoints
+type AuditConfig struct {
+	SigningKey         ed25519.PrivateKey // signs checkpoints, nil disables them
+	PublicKey          ed25519.PublicKey  // verifies checkpoints, derived from SigningKey when not set
+	CheckpointInterval time.Duration      // how often a checkpoint is written (default: 1h)
+}
+
+// GetAuditConfig reads the AUDIT_* environment variables.
+// AUDIT_SIGNING_KEY is a base64 Ed25519 seed (32 bytes), AUDIT_PUBLIC_KEY a base64 public key
+// for verifying without the private key. Generate a pair with "audit keygen".
+func GetAuditConfig() AuditConfig {
+	cfg := AuditConfig{CheckpointInterval: time.Hour}
+	if value := os.Getenv("AUDIT_CHECKPOINT_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			cfg.CheckpointInterval = interval
+		} else {
+			log.Printf("invalid AUDIT_CHECKPOINT_INTERVAL %q, using %s", value, cfg.CheckpointInterval)
+		}
+	}
+
+	if value := os.Getenv("AUDIT_SIGNING_KEY"); value != "" {
+		seed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			log.Fatalf("AUDIT_SIGNING_KEY must be a base64 encoded %d byte Ed25519 seed", ed25519.SeedSize)
+		}
+		cfg.SigningKey = ed25519.NewKeyFromSeed(seed)
+		cfg.PublicKey = cfg.SigningKey.Public().(ed25519.PublicKey)
+	}
+	if value := os.Getenv("AUDIT_PUBLIC_KEY"); value != "" {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			log.Fatalf("AUDIT_PUBLIC_KEY must be a base64 encoded %d byte Ed25519 public key", ed25519.PublicKeySize)
+		}
+		cfg.PublicKey = key
+	}
+	return cfg
+}
+
+// MailerConfig holds the settings of the mail backend
+type MailerConfig struct {
+	Backend  string // "log" (default) or "smtp"
+	Host     string
+	Port     string // default: 587
+	Username string // optional, enables PLAIN auth
+	Password string
+	From     string // sender address
+}
+
+// GetMailerConfig reads MAILER and the SMTP_* / MAIL_FROM environment variables
+func GetMailerConfig() MailerConfig {
+	return MailerConfig{
+		Backend:  getEnvDefault("MAILER", "log"),
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     getEnvDefault("SMTP_PORT", "587"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	}
+}
+
+// GetGeoIPDBPath returns the local MaxMind database used for country lookups ("" disables them)
+func GetGeoIPDBPath() string {
+	return os.Getenv("GEOIP_DB_PATH")
+}
+
+// GetDBTimeout returns how long a request may spend on database queries (DB_TIMEOUT, default
+// 10s). Queries past it are cancelled and answered with 504; "0" disables the limit.
+func GetDBTimeout() time.Duration {
+	timeout := 10 * time.Second
+	if value := os.Getenv("DB_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			timeout = parsed
+		} else {
+			log.Printf("invalid DB_TIMEOUT %q, using %s", value, timeout)
+		}
+	}
+	return timeout
+}
+
+// GetDBSlowQueryThreshold returns how long a query runs before it is logged as slow
+// (DB_SLOW_QUERY_THRESHOLD, default 200ms, 0 disables the log)
+func GetDBSlowQueryThreshold() time.Duration {
+	threshold := 200 * time.Millisecond
+	if value := os.Getenv("DB_SLOW_QUERY_THRESHOLD"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			threshold = parsed
+		} else {
+			log.Printf("invalid DB_SLOW_QUERY_THRESHOLD %q, using %s", value, threshold)
+		}
+	}
+	return threshold
+}
+
+// GetProblemTypeBase returns the prefix of the "type" URI of problem+json error responses
+// (PROBLEM_TYPE_BASE_URL, default "/problems/"); the error code is appended to it
+func GetProblemTypeBase() string {
+	if base := os.Getenv("PROBLEM_TYPE_BASE_URL"); base != "" {
+		return base
+	}
+	return "/problems/"
+}
+
+// User purge modes, what happens to users deleted longer ago than the retention
+const (
+	UserPurgeDelete    = "delete"    // remove the rows
+	UserPurgeAnonymize = "anonymize" // scrub name, email, password and age, keep the rows
+)
+
+// UserPurgeConfig holds the settings of the background purge of soft-deleted users
+type UserPurgeConfig struct {
+	Retention time.Duration // how long deleted users can be restored, 0 disables the purge
+	Interval  time.Duration // how often the purge runs (default: 1h)
+	Mode      string        // UserPurgeDelete or UserPurgeAnonymize (default)
+}
+
+// GetUserPurgeConfig reads USER_RETENTION_DAYS, USER_PURGE_INTERVAL and USER_PURGE_MODE
+func GetUserPurgeConfig() UserPurgeConfig {
+	cfg := UserPurgeConfig{Interval: time.Hour, Mode: getEnvDefault("USER_PURGE_MODE", UserPurgeAnonymize)}
+	if value := os.Getenv("USER_RETENTION_DAYS"); value != "" {
+		if days, err := strconv.Atoi(value); err == nil && days >= 0 {
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		} else {
+			log.Printf("invalid USER_RETENTION_DAYS %q, deleted users are kept", value)
+		}
+	}
+	if value := os.Getenv("USER_PURGE_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			cfg.Interval = interval
+		} else {
+			log.Printf("invalid USER_PURGE_INTERVAL %q, using %s", value, cfg.Interval)
+		}
+	}
+	if cfg.Mode != UserPurgeDelete && cfg.Mode != UserPurgeAnonymize {
+		log.Printf("unknown USER_PURGE_MODE %q, using %s", cfg.Mode, UserPurgeAnonymize)
+		cfg.Mode = UserPurgeAnonymize
+	}
+	return cfg
+}
+
+// GetPublicURL returns the URL the API is reached at from outside (PUBLIC_URL, default
+// http://localhost:8080), used to build the links sent by email
+func GetPublicURL() string {
+	if url := os.Getenv("PUBLIC_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// User cache backends, where GetUserByID / GetUserByEmail results are kept
+const (
+	UserCacheOff    = "off"    // every lookup hits the database
+	UserCacheMemory = "memory" // LRU in process memory, per replica
+	UserCacheRedis  = "redis"  // shared by the replicas, see REDIS_URL
+)
+
+// UserCacheConfig holds the settings of the user lookup cache
+type UserCacheConfig struct {
+	Backend  string        // UserCacheOff (default), UserCacheMemory or UserCacheRedis
+	Size     int           // entries of the memory backend (default: 10000)
+	TTL      time.Duration // how long a cached user is used (default: 1m)
+	RedisURL string        // redis://[user:password@]host:port[/db]
+}
+
+// GetUserCacheConfig reads USER_CACHE, USER_CACHE_SIZE, USER_CACHE_TTL and REDIS_URL
+func GetUserCacheConfig() UserCacheConfig {
+	cfg := UserCacheConfig{
+		Backend:  strings.ToLower(getEnvDefault("USER_CACHE", UserCacheOff)),
+		Size:     10000,
+		TTL:      time.Minute,
+		RedisURL: getEnvDefault("REDIS_URL", "redis://localhost:6379/0"),
+	}
+	if value := os.Getenv("USER_CACHE_SIZE"); value != "" {
+		if size, err := strconv.Atoi(value); err == nil && size > 0 {
+			cfg.Size = size
+		} else {
+			log.Printf("invalid USER_CACHE_SIZE %q, using %d", value, cfg.Size)
+		}
+	}
+	if value := os.Getenv("USER_CACHE_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+			cfg.TTL = ttl
+		} else {
+			log.Printf("invalid USER_CACHE_TTL %q, using %s", value, cfg.TTL)
+		}
+	}
+	return cfg
+}
@@ -2,12 +2,13 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
-	"time"
 
-	"github.com/devesh121/userAuth/internals/models"
+	"github.com/devesh121/userAuth/pkg/migrate"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -15,8 +16,9 @@ import (
 
 var DB *gorm.DB // Global DB instance accessible across the app
 
-// ConnectDB initializes and opens a DB connection using the configuration from .env
-func ConnectDB() {
+// OpenDB initializes and opens a DB connection using the configuration from .env, without
+// looking at the schema
+func OpenDB() {
 
 	LoadEnv() // Load environment variables from .env file
 
@@ -25,21 +27,24 @@ func ConnectDB() {
 		log.Fatalf("Failed to load database configuration: %v", err)
 	}
 
-	// Create the PostgreSQL DSN (Data Source Name) string
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.DB_Name, dbConfig.SSLMode)
+	// Create the PostgreSQL DSN (Data Source Name) string, or open the SQLite file
+	dialector := postgres.Open(fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.DB_Name, dbConfig.SSLMode))
+	if dbConfig.Driver == "sqlite" {
+		dialector = sqlite.Open(dbConfig.SQLitePath)
+	}
 
 	// Configure GORM with connection pooling and logging (for development/debugging)
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
 		logger.Config{
-			SlowThreshold: time.Second, // Slow SQL threshold
+			SlowThreshold: 0,           // Slow SQL is logged with its request ID by metrics.GormPlugin
 			LogLevel:      logger.Info, // Log level (adjust for production)
 			Colorful:      true,        // Disable color in production
 		},
 	)
-	// Open a new connection using GORM and the PostgreSQL driver
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	// Open a new connection using GORM and the selected driver
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger:                                   newLogger,
 		SkipDefaultTransaction:                   true, // Recommended for performance in some cases
 		PrepareStmt:                              true, // Improves performance for repeated queries
@@ -49,11 +54,51 @@ func ConnectDB() {
 		log.Fatal("Failed to connect to the database: ", err) // Exit if connection fails
 	}
 
+	// SQLite allows one writer; a single connection also keeps ":memory:" one database
+	if dbConfig.Driver == "sqlite" {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			log.Fatal("Failed to access the database connection: ", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
 	log.Println("✅ Database connection successful")
+}
+
+// ConnectDB opens the database and refuses to start unless the schema is at the version this
+// build expects. With DB_AUTO_MIGRATE=true pending migrations are applied first (under the
+// migration lock, so replicas starting together are safe)
+func ConnectDB() {
+	OpenDB()
+
+	migrator, err := NewMigrator()
+	if err != nil {
+		log.Fatalf("❌ Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	if os.Getenv("DB_AUTO_MIGRATE") == "true" {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("❌ Failed to migrate the database: %v", err)
+		}
+		for _, m := range applied {
+			log.Printf("✅ Applied migration %04d_%s", m.Version, m.Name)
+		}
+	}
 
-	//Auto migrating the models for table creation on psql database
-	if err := DB.AutoMigrate(&models.User{}); err != nil {
-		log.Fatalf("❌ Failed to auto migrate models: %v", err)
+	if err := migrator.Check(ctx); err != nil {
+		log.Fatalf("❌ %v (run \"migrate up\" first)", err)
+	}
+	log.Println("✅ Database schema is up to date")
+}
+
+// NewMigrator returns the migrator of the embedded SQL migrations for the open database
+func NewMigrator() (*migrate.Migrator, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
 	}
-	log.Println("✅ Database migration completed")
+	return migrate.New(sqlDB, DB.Dialector.Name())
 }
@@ -0,0 +1,96 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/devesh121/userAuth/pkg/migrate"
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadPairsAndSorts checks that up/down files are paired by version and sorted
+func TestLoadPairsAndSorts(t *testing.T) {
+	dir := fstest.MapFS{
+		"0002_add_index.up.sql":        {Data: []byte("CREATE INDEX ...")},
+		"0002_add_index.down.sql":      {Data: []byte("DROP INDEX ...")},
+		"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE ...")},
+		"0001_initial_schema.down.sql": {Data: []byte("DROP TABLE ...")},
+		"README.md":                    {Data: []byte("ignored")},
+	}
+
+	migrations, err := migrate.Load(dir)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+	assert.Equal(t, "DROP INDEX ...", migrations[1].Down)
+
+	// A migration without its down file can't be reverted, so it is rejected
+	delete(dir, "0002_add_index.down.sql")
+	_, err = migrate.Load(dir)
+	assert.Error(t, err)
+}
+
+// TestEmbeddedMigrations checks that the shipped migrations load
+func TestEmbeddedMigrations(t *testing.T) {
+	migrator, err := migrate.New(nil, "postgres")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, migrator.Latest(), int64(1))
+
+	_, err = migrate.New(nil, "oracle")
+	assert.Error(t, err)
+}
+
+// TestCreateNumbersAfterLatest checks the file names written by "migrate create"
+func TestCreateNumbersAfterLatest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0007_users.up.sql"), []byte("-- up"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0007_users.down.sql"), []byte("-- down"), 0o644))
+
+	paths, err := migrate.Create(dir, "Add Phone Number")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0008_add_phone_number.up.sql"),
+		filepath.Join(dir, "0008_add_phone_number.down.sql"),
+	}, paths)
+}
+
+// TestUpDownOnSQLite runs the sqlite migrations up and down on a throwaway database
+func TestUpDownOnSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := migrate.New(db, "sqlite")
+	require.NoError(t, err)
+	assert.ErrorIs(t, migrator.Check(ctx), migrate.ErrVersionMismatch)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, applied)
+	require.NoError(t, migrator.Check(ctx))
+
+	// Up again is a no-op
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+
+	reverted, err := migrator.Down(ctx, len(statuses))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(statuses))
+	current, err := migrator.Current(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, current)
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "login_attempts";
+DROP TABLE IF EXISTS "audit_checkpoints";
+DROP TABLE IF EXISTS "audit_events";
+DROP FUNCTION IF EXISTS audit_append_only();
+DROP TABLE IF EXISTS "sessions";
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "linked_identities";
+DROP TABLE IF EXISTS "revoked_tokens";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,149 @@
+-- Schema as created by GORM AutoMigrate before versioned migrations.
+-- IF NOT EXISTS everywhere so databases created by AutoMigrate are adopted as version 1.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text,
+    "email" text,
+    "password" text,
+    "age" bigint,
+    "role" text DEFAULT 'user',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "revoked_tokens" (
+    "id" bigserial,
+    "jti" varchar(64) NOT NULL,
+    "user_id" bigint,
+    "expires_at" timestamptz,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_revoked_tokens_jti" ON "revoked_tokens" ("jti");
+CREATE INDEX IF NOT EXISTS "idx_revoked_tokens_expires_at" ON "revoked_tokens" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_revoked_tokens_user_id" ON "revoked_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "linked_identities" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    "provider" varchar(64) NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_linked_identities_user_id" ON "linked_identities" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_linked_identities_deleted_at" ON "linked_identities" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_identity_provider_subject" ON "linked_identities" ("provider", "subject");
+
+CREATE TABLE IF NOT EXISTS "api_keys" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "prefix" varchar(16) NOT NULL,
+    "key_hash" varchar(64) NOT NULL,
+    "scopes" text,
+    "expires_at" timestamptz,
+    "revoked_at" timestamptz,
+    "last_used_at" timestamptz,
+    "last_used_ip" varchar(45),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_api_keys_deleted_at" ON "api_keys" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_key_hash" ON "api_keys" ("key_hash");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_prefix" ON "api_keys" ("prefix");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_user_id" ON "api_keys" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "id" varchar(64),
+    "user_id" bigint NOT NULL,
+    "auth_method" varchar(32),
+    "user_agent" varchar(512),
+    "ip" varchar(45),
+    "created_at" timestamptz,
+    "last_seen_at" timestamptz,
+    "expires_at" timestamptz,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_sessions_expires_at" ON "sessions" ("expires_at");
+
+CREATE TABLE IF NOT EXISTS "audit_events" (
+    "id" bigserial,
+    "event_type" varchar(64) NOT NULL,
+    "actor_id" bigint,
+    "actor_email" varchar(255),
+    "target_type" varchar(32),
+    "target_id" varchar(64),
+    "ip" varchar(45),
+    "user_agent" varchar(512),
+    "metadata" jsonb,
+    "created_at" timestamptz NOT NULL,
+    "prev_hash" varchar(64),
+    "hash" varchar(64) NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_events_prev_hash" ON "audit_events" ("prev_hash");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_created_at" ON "audit_events" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_target" ON "audit_events" ("target_type", "target_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_actor_id" ON "audit_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_event_type" ON "audit_events" ("event_type");
+
+CREATE TABLE IF NOT EXISTS "audit_checkpoints" (
+    "id" bigserial,
+    "event_id" bigint NOT NULL,
+    "event_hash" varchar(64) NOT NULL,
+    "key_id" varchar(16),
+    "signature" text NOT NULL,
+    "created_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_checkpoints_event_id" ON "audit_checkpoints" ("event_id");
+
+CREATE TABLE IF NOT EXISTS "login_attempts" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "success" boolean NOT NULL,
+    "reason" varchar(255),
+    "auth_method" varchar(32),
+    "ip" varchar(45),
+    "network" varchar(64),
+    "country" varchar(2),
+    "user_agent" varchar(512),
+    "fingerprint" varchar(16),
+    "new_device" boolean,
+    "new_network" boolean,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_fingerprint" ON "login_attempts" ("fingerprint");
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_network" ON "login_attempts" ("network");
+CREATE INDEX IF NOT EXISTS "idx_login_user_created" ON "login_attempts" ("user_id", "created_at");
+
+-- The audit tables are append-only
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_append_only();
+
+DROP TRIGGER IF EXISTS audit_checkpoints_append_only ON audit_checkpoints;
+CREATE TRIGGER audit_checkpoints_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_checkpoints
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_append_only();
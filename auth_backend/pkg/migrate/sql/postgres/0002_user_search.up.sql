@@ -0,0 +1,13 @@
+-- Full-text and fuzzy search over users (GET /users/search).
+-- search_vector holds the words of the name and of the email (split at @ . _ + -), the trigram
+-- indexes serve substring (ILIKE) and similarity matches.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE "users" ADD COLUMN "search_vector" tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', coalesce("name", '') || ' ' || regexp_replace(coalesce("email", ''), '[@._+-]+', ' ', 'g'))
+) STORED;
+
+CREATE INDEX "idx_users_search_vector" ON "users" USING gin ("search_vector");
+CREATE INDEX "idx_users_name_trgm" ON "users" USING gin ("name" gin_trgm_ops);
+CREATE INDEX "idx_users_email_trgm" ON "users" USING gin ("email" gin_trgm_ops);
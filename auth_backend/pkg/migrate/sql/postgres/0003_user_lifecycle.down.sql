@@ -0,0 +1,4 @@
+-- Fails while a deleted user and a live one share an email: purge or rename them first.
+ALTER TABLE "users" DROP COLUMN IF EXISTS "anonymized_at";
+DROP INDEX IF EXISTS "idx_users_email_live";
+ALTER TABLE "users" ADD CONSTRAINT "uni_users_email" UNIQUE ("email");
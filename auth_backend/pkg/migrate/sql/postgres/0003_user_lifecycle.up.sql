@@ -0,0 +1,8 @@
+-- Soft-deleted users free their email: it is only unique among live rows, so a deleted account
+-- doesn't block registering again. anonymized_at marks deleted users whose personal data was
+-- scrubbed by the purge (USER_PURGE_MODE=anonymize), they can't be restored.
+
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "uni_users_email";
+CREATE UNIQUE INDEX "idx_users_email_live" ON "users" ("email") WHERE "deleted_at" IS NULL;
+
+ALTER TABLE "users" ADD COLUMN "anonymized_at" timestamptz;
@@ -0,0 +1,3 @@
+-- Optimistic locking of users: every update bumps version and only applies to the version it
+-- was made from. Served as the ETag of GET /users/:id and required in If-Match on updates.
+ALTER TABLE "users" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- When the user proved to own their email, NULL until the link sent to it is opened. Changing
+-- the email clears it. Accounts from before verification existed count as verified.
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamptz;
+UPDATE "users" SET "email_verified_at" = "created_at";
@@ -0,0 +1,2 @@
+-- An email change waits here until the link sent to the new address is opened.
+ALTER TABLE "users" ADD COLUMN "pending_email" text NOT NULL DEFAULT '';
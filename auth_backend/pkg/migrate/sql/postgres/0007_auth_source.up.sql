@@ -0,0 +1,3 @@
+-- The external identity source (ldap, saml) that provisioned the user, '' for local accounts.
+-- Only that source may log the user in and sync its name and role.
+ALTER TABLE "users" ADD COLUMN "auth_source" text NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `login_attempts`;
+DROP TABLE IF EXISTS `audit_checkpoints`;
+DROP TABLE IF EXISTS `audit_events`;
+DROP TABLE IF EXISTS `sessions`;
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `linked_identities`;
+DROP TABLE IF EXISTS `revoked_tokens`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,134 @@
+-- Same schema as postgres/0001, in SQLite types, for local development and tests.
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `name` text,
+    `email` text,
+    `password` text,
+    `age` integer,
+    `role` text DEFAULT 'user',
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `revoked_tokens` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `jti` text NOT NULL,
+    `user_id` integer,
+    `expires_at` datetime,
+    `revoked_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_revoked_tokens_jti` ON `revoked_tokens` (`jti`);
+CREATE INDEX IF NOT EXISTS `idx_revoked_tokens_expires_at` ON `revoked_tokens` (`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_revoked_tokens_user_id` ON `revoked_tokens` (`user_id`);
+
+CREATE TABLE IF NOT EXISTS `linked_identities` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` integer NOT NULL,
+    `provider` text NOT NULL,
+    `subject` text NOT NULL,
+    `email` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_identity_provider_subject` ON `linked_identities` (`provider`, `subject`);
+CREATE INDEX IF NOT EXISTS `idx_linked_identities_user_id` ON `linked_identities` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_linked_identities_deleted_at` ON `linked_identities` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` integer NOT NULL,
+    `name` text NOT NULL,
+    `prefix` text NOT NULL,
+    `key_hash` text NOT NULL,
+    `scopes` text,
+    `expires_at` datetime,
+    `revoked_at` datetime,
+    `last_used_at` datetime,
+    `last_used_ip` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_keys_key_hash` ON `api_keys` (`key_hash`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_prefix` ON `api_keys` (`prefix`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_user_id` ON `api_keys` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_api_keys_deleted_at` ON `api_keys` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` text,
+    `user_id` integer NOT NULL,
+    `auth_method` text,
+    `user_agent` text,
+    `ip` text,
+    `created_at` datetime,
+    `last_seen_at` datetime,
+    `expires_at` datetime,
+    `revoked_at` datetime,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_sessions_expires_at` ON `sessions` (`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_user_id` ON `sessions` (`user_id`);
+
+CREATE TABLE IF NOT EXISTS `audit_events` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `event_type` text NOT NULL,
+    `actor_id` integer,
+    `actor_email` text,
+    `target_type` text,
+    `target_id` text,
+    `ip` text,
+    `user_agent` text,
+    `metadata` jsonb,
+    `created_at` datetime NOT NULL,
+    `prev_hash` text,
+    `hash` text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_actor_id` ON `audit_events` (`actor_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_event_type` ON `audit_events` (`event_type`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_audit_events_prev_hash` ON `audit_events` (`prev_hash`);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_created_at` ON `audit_events` (`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_audit_target` ON `audit_events` (`target_type`, `target_id`);
+
+CREATE TABLE IF NOT EXISTS `audit_checkpoints` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `event_id` integer NOT NULL,
+    `event_hash` text NOT NULL,
+    `key_id` text,
+    `signature` text NOT NULL,
+    `created_at` datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_checkpoints_event_id` ON `audit_checkpoints` (`event_id`);
+
+-- The audit log is append-only
+CREATE TRIGGER IF NOT EXISTS `audit_events_no_update` BEFORE UPDATE ON `audit_events`
+BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS `audit_events_no_delete` BEFORE DELETE ON `audit_events`
+BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS `audit_checkpoints_no_update` BEFORE UPDATE ON `audit_checkpoints`
+BEGIN SELECT RAISE(ABORT, 'audit_checkpoints is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS `audit_checkpoints_no_delete` BEFORE DELETE ON `audit_checkpoints`
+BEGIN SELECT RAISE(ABORT, 'audit_checkpoints is append-only'); END;
+
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `success` numeric NOT NULL,
+    `reason` text,
+    `auth_method` text,
+    `ip` text,
+    `network` text,
+    `country` text,
+    `user_agent` text,
+    `fingerprint` text,
+    `new_device` numeric,
+    `new_network` numeric,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_login_attempts_fingerprint` ON `login_attempts` (`fingerprint`);
+CREATE INDEX IF NOT EXISTS `idx_login_attempts_network` ON `login_attempts` (`network`);
+CREATE INDEX IF NOT EXISTS `idx_login_user_created` ON `login_attempts` (`user_id`, `created_at`);
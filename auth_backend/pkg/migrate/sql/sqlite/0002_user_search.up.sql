@@ -0,0 +1,3 @@
+-- SQLite has no tsvector or trigram indexes: user search scans with LIKE and ranks in Go
+-- (sqliteUserRepository.SearchUsers). Kept so the versions stay in step with PostgreSQL.
+SELECT 1;
@@ -0,0 +1,19 @@
+-- Fails while a deleted user and a live one share an email: purge or rename them first.
+CREATE TABLE `users_old` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `name` text,
+    `email` text,
+    `password` text,
+    `age` integer,
+    `role` text DEFAULT 'user',
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+INSERT INTO `users_old` (`id`, `created_at`, `updated_at`, `deleted_at`, `name`, `email`, `password`, `age`, `role`)
+    SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `name`, `email`, `password`, `age`, `role` FROM `users`;
+DROP TABLE `users`;
+ALTER TABLE `users_old` RENAME TO `users`;
+
+CREATE INDEX `idx_users_deleted_at` ON `users` (`deleted_at`);
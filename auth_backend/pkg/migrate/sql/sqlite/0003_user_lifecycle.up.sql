@@ -0,0 +1,22 @@
+-- Same as postgres/0003. SQLite can't drop a table constraint, so users is rebuilt without the
+-- unique email constraint, then the email is made unique among rows that aren't deleted.
+
+CREATE TABLE `users_new` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `name` text,
+    `email` text,
+    `password` text,
+    `age` integer,
+    `role` text DEFAULT 'user',
+    `anonymized_at` datetime
+);
+INSERT INTO `users_new` (`id`, `created_at`, `updated_at`, `deleted_at`, `name`, `email`, `password`, `age`, `role`)
+    SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `name`, `email`, `password`, `age`, `role` FROM `users`;
+DROP TABLE `users`;
+ALTER TABLE `users_new` RENAME TO `users`;
+
+CREATE INDEX `idx_users_deleted_at` ON `users` (`deleted_at`);
+CREATE UNIQUE INDEX `idx_users_email_live` ON `users` (`email`) WHERE `deleted_at` IS NULL;
@@ -0,0 +1,2 @@
+-- Same as postgres/0004.
+ALTER TABLE `users` ADD COLUMN `version` integer NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- Same as postgres/0005.
+ALTER TABLE `users` ADD COLUMN `email_verified_at` datetime;
+UPDATE `users` SET `email_verified_at` = `created_at`;
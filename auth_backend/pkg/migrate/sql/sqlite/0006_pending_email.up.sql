@@ -0,0 +1,2 @@
+-- Same as postgres/0006.
+ALTER TABLE `users` ADD COLUMN `pending_email` text NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Same as postgres/0007.
+ALTER TABLE `users` ADD COLUMN `auth_source` text NOT NULL DEFAULT '';